@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const envPrefix = "CRYPTO_TRACKER"
+
+// the google oauth client secret and cached token live in the per-user config
+// directory, so commands behave the same from any working directory
+var (
+	defaultCredentials = userConfigPath("credentials.json")
+	defaultToken       = userConfigPath("token.json")
+)
+
+// userConfigPath resolves a file under $XDG_CONFIG_HOME/crypto-tracker (or the
+// platform equivalent), falling back to the working directory.
+func userConfigPath(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return name
+	}
+	return filepath.Join(dir, "crypto-tracker", name)
+}
+
+type setting struct {
+	Default interface{}
+	Usage   string
+}
+
+// settings lists every key that may be set in the config file, the
+// environment or by the matching command line flag.
+var settings = map[string]setting{
+	"account-id":          {"", "cyrpto.org account id"},
+	"auth":                {authOAuth, "how to authenticate with google: oauth, service-account or adc"},
+	"create-spreadsheet":  {false, "create a new spreadsheet when no spreadsheet-id is set"},
+	"credentials":         {defaultCredentials, "path to the google oauth client secret file"},
+	"daemon-interval":     {defaultDaemonInterval, "how often the daemon refreshes prices"},
+	"explorer":            {defaultExplorer, "crypto.org explorer api url"},
+	"fiat":                {defaultFiat, "type of fiat to use (USD or EUR)"},
+	"format":              {defaultExportFormat, "format export converts to: koinly or cointracker"},
+	"history-sheet":       {"", "name of the google sheet import writes the daily history to, skipped when empty"},
+	"file":                {defaultTransactionsFile, "cyrpto transactions csv files or globs, comma separated"},
+	"no-browser":          {false, "log in by pasting the redirect URL instead of opening a browser"},
+	"output":              {outputTable, "report output format: table, json, yaml or csv"},
+	"profile":             {"", "named profile from the config file to apply"},
+	"prices-sheet":        {tracker.DefaultPricesSheet, "name of the google sheet holding the prices the table is valued at"},
+	"spreadsheet-id":      {"", "id of google sheet (found in the URL)"},
+	"spreadsheet-name":    {defaultSpreadsheetName, "name of google sheet"},
+	"service-account-key": {"", "path to a google service account json key, used when auth is service-account"},
+	"snapshot-sheet":      {tracker.DefaultSnapshotSheet, "name of the google sheet the daemon appends portfolio snapshots to"},
+	"start-cell":          {"", "top left cell of the table in A1 notation, overrides start-row and start-column"},
+	"start-column":        {defaultStartColumn, "column of the top left cell of the table"},
+	"start-row":           {defaultStartRow, "row of the top left cell of the table"},
+	"token":               {defaultToken, "path to the cached google oauth token"},
+	"token-store":         {tokenStoreFile, "how to store the oauth token: file or encrypted"},
+	"wallets":             {[]string{}, "cyrpto.org account ids tracked alongside account-id"},
+	"watch-interval":      {defaultWatchInterval, "how often import --watch checks the file for changes"},
+}
+
+// applyProfile overlays the settings of the selected profile, found under
+// profiles.<name> in the config file, on top of the top level settings.
+// Flags and environment variables still take precedence.
+func applyProfile() error {
+	name := viper.GetString("profile")
+	if name == "" {
+		return nil
+	}
+	profile := viper.GetStringMap(profileKey(name, ""))
+	if len(profile) == 0 {
+		return fmt.Errorf("profile %q not found in config file", name)
+	}
+	return viper.MergeConfigMap(profile)
+}
+
+// formatsKey lists column mappings for exports without a built in format.
+// It can only be set in the config file, so it isn't one of the settings.
+const formatsKey = "formats"
+
+// registerFormats adds the column mappings of the config file to the formats
+// exports are recognized by.
+func registerFormats() error {
+	var mappings []tracker.Mapping
+	if err := viper.UnmarshalKey(formatsKey, &mappings); err != nil {
+		return fmt.Errorf("invalid %s; %w", formatsKey, err)
+	}
+	for _, mapping := range mappings {
+		format, err := tracker.NewMappingFormat(mapping)
+		if err != nil {
+			return fmt.Errorf("invalid %s; %w", formatsKey, err)
+		}
+		tracker.RegisterFormat(format)
+	}
+	return nil
+}
+
+func profileKey(name, key string) string {
+	if key == "" {
+		return "profiles." + name
+	}
+	return "profiles." + name + "." + key
+}
+
+// accountIDs is every account selected by account-id and wallets, each
+// listed once.
+func accountIDs() []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, id := range append([]string{viper.GetString("account-id")}, viper.GetStringSlice("wallets")...) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func setDefaults() {
+	for key, s := range settings {
+		viper.SetDefault(key, s.Default)
+	}
+}
+
+// bindFlags makes the flags of the command being executed the highest
+// precedence source for their viper keys. Binding happens at run time since
+// several commands share key names.
+func bindFlags(cmd *cobra.Command, args []string) error {
+	return viper.BindPFlags(cmd.Flags())
+}
+
+// configFilePath is the file `config set` writes to.
+func configFilePath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	if used := viper.ConfigFileUsed(); used != "" {
+		return used, nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".crypto-tracker.yaml"), nil
+}
+
+func NewConfigCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "View, set and validate crypto-tracker settings",
+	}
+	command.AddCommand(newConfigViewCommand())
+	command.AddCommand(newConfigSetCommand())
+	command.AddCommand(newConfigValidateCommand())
+	return command
+}
+
+func newConfigViewCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "view",
+		Short: "Print the effective value of every setting",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			if used := viper.ConfigFileUsed(); used != "" {
+				fmt.Fprintf(out, "config file: %s\n", used)
+			}
+			if profile := viper.GetString("profile"); profile != "" {
+				fmt.Fprintf(out, "profile: %s\n", profile)
+			}
+			fmt.Fprintln(out)
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tVALUE\tENV\tDESCRIPTION")
+			for _, key := range settingKeys() {
+				fmt.Fprintf(w, "%s\t%v\t%s\t%s\n", key, viper.Get(key), envName(key), settings[key].Usage)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func newConfigSetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Write a setting to the config file, or to a profile with --profile",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, value := args[0], args[1]
+			if _, ok := settings[key]; !ok {
+				return configError(fmt.Errorf("unknown setting %q", key))
+			}
+			profile := viper.GetString("profile")
+			if profile != "" && key == "profile" {
+				return configError(errors.New("profiles can't select another profile"))
+			}
+			if err := validateSetting(key, value); err != nil {
+				return configError(err)
+			}
+			path, err := configFilePath()
+			if err != nil {
+				return configError(err)
+			}
+			// only the file contents are rewritten, never values that came
+			// from flags or the environment
+			file := viper.New()
+			file.SetConfigFile(path)
+			if err := file.ReadInConfig(); err != nil && !os.IsNotExist(err) {
+				return configError(fmt.Errorf("failed to read %s; %w", path, err))
+			}
+			var stored interface{} = value
+			if key == "wallets" {
+				stored = strings.Split(value, ",")
+			}
+			if profile != "" {
+				key = profileKey(profile, key)
+			}
+			file.Set(key, stored)
+			if err := file.WriteConfigAs(path); err != nil {
+				return fmt.Errorf("failed to write %s; %w", path, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s set in %s\n", key, path)
+			return nil
+		},
+	}
+}
+
+func newConfigValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the config file and effective settings for mistakes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var errs []string
+			for _, key := range viper.AllKeys() {
+				if !viper.InConfig(key) {
+					continue
+				}
+				name := key
+				if parts := strings.SplitN(key, ".", 3); parts[0] == "profiles" && len(parts) == 3 {
+					name = parts[2]
+				}
+				if name == formatsKey {
+					continue
+				}
+				if _, ok := settings[name]; !ok || (name == "profile" && name != key) {
+					errs = append(errs, fmt.Sprintf("unknown setting %q", key))
+				}
+			}
+			for _, key := range settingKeys() {
+				if key == "credentials" && viper.GetString("auth") != authOAuth {
+					continue
+				}
+				value := viper.GetString(key)
+				if key == "file" {
+					value = strings.Join(exportPatterns(), ",")
+				}
+				if err := validateSetting(key, value); err != nil {
+					errs = append(errs, err.Error())
+				}
+			}
+			if len(errs) > 0 {
+				return configError(errors.New(strings.Join(errs, "\n")))
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "config is valid")
+			return nil
+		},
+	}
+}
+
+func validateSetting(key, value string) error {
+	switch key {
+	case "spreadsheet-id":
+		// import creates the spreadsheet when asked to
+		if value == "" && !viper.GetBool("create-spreadsheet") {
+			return errors.New("Missing spreadsheet-id, set one or enable create-spreadsheet")
+		}
+	case "create-spreadsheet", "no-browser":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", key, value)
+		}
+	case "fiat":
+		if value != "USD" && value != "EUR" {
+			return fmt.Errorf("fiat must be USD or EUR, got %q", value)
+		}
+	case "start-row":
+		var row int64
+		if _, err := fmt.Sscan(value, &row); err != nil || row < 1 {
+			return fmt.Errorf("start-row must be a positive number, got %q", value)
+		}
+	case "start-column":
+		if _, err := tracker.ColumnIndex(value); err != nil {
+			return fmt.Errorf("start-column must be column letters, got %q", value)
+		}
+	case "start-cell":
+		if value == "" {
+			return nil
+		}
+		if _, _, err := tracker.ParseCell(value); err != nil {
+			return fmt.Errorf("start-cell must be an A1 cell such as C5, got %q", value)
+		}
+	case "auth":
+		switch value {
+		case authOAuth, authServiceAccount, authADC:
+		default:
+			return fmt.Errorf("auth must be oauth, service-account or adc, got %q", value)
+		}
+	case "output":
+		switch value {
+		case outputTable, outputJSON, outputYAML, outputCSV:
+		default:
+			return fmt.Errorf("output must be table, json, yaml or csv, got %q", value)
+		}
+	case "format":
+		if _, err := tracker.TaxFormatNamed(value); err != nil {
+			return err
+		}
+	case "token-store":
+		if _, err := newTokenStore(value, ""); err != nil {
+			return err
+		}
+	case "service-account-key":
+		if value == "" {
+			return nil
+		}
+		if _, err := os.Stat(value); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+	case "watch-interval", "daemon-interval":
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("%s must be a positive duration such as 1m, got %q", key, value)
+		}
+	case "file":
+		paths, err := expandExports(strings.Split(value, ","))
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		for _, path := range paths {
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+		}
+	case "credentials":
+		if _, err := os.Stat(value); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+func settingKeys() []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func envName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
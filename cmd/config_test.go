@@ -0,0 +1,286 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// readConfig loads a yaml config file for the rest of the test.
+func readConfig(t *testing.T, config string) {
+	t.Helper()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterFormats(t *testing.T) {
+	readConfig(t, `
+formats:
+  - name: config-test-exchange
+    fiat: USD
+    timestamp-layout: "2006/01/02"
+    columns:
+      timestamp: Day
+      kind: What
+      asset: 3
+      amount: Units
+      fiat-amount: Cost
+    kinds:
+      - match: "^bought$"
+        kind: buy
+      - match: ".*"
+        kind: adjustment
+`)
+	if err := registerFormats(); err != nil {
+		t.Fatal(err)
+	}
+	transactions, err := tracker.ParseTransactions(strings.NewReader("Day,What,Coin,Units,Cost\n2021/03/01,bought,CRO,100,12\n2021/03/02,moved,CRO,-10,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	purchases := tracker.Purchases(transactions, "USD")
+	if len(transactions) != 2 || len(purchases) != 1 || purchases[0].Fiat != 12 || purchases[0].CRO != 100 {
+		t.Errorf("parsed %+v, want a purchase of 100 CRO for 12 USD and an adjustment", transactions)
+	}
+}
+
+func TestRegisterFormatsInvalid(t *testing.T) {
+	readConfig(t, `
+formats:
+  - name: config-test-broken
+    columns:
+      timestamp: Day
+      asset: Coin
+`)
+	err := registerFormats()
+	if err == nil || !strings.Contains(err.Error(), "config-test-broken: missing amount column") {
+		t.Errorf("err = %v, want the missing amount column reported", err)
+	}
+}
+
+// setEnv sets an environment variable for the rest of the test.
+func setEnv(t *testing.T, key, value string) {
+	t.Helper()
+	original, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, original)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// executeRoot runs the root command, with extra commands added to it,
+// reading config as the config file.
+func executeRoot(t *testing.T, config string, extra []*cobra.Command, args ...string) error {
+	t.Helper()
+	_, err := executeRootOutput(t, config, extra, args...)
+	return err
+}
+
+// executeRootOutput is executeRoot returning what the command printed.
+func executeRootOutput(t *testing.T, config string, extra []*cobra.Command, args ...string) (string, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cfgFile = ""
+		viper.Reset()
+	})
+	var out bytes.Buffer
+	root := NewRootCommand()
+	root.AddCommand(extra...)
+	root.SetOut(&out)
+	root.SetErr(ioutil.Discard)
+	root.SetArgs(append([]string{"--config", path}, args...))
+	err := root.Execute()
+	return out.String(), err
+}
+
+// probeSettings runs a command through the root command, so the config
+// file, environment and flags are resolved the way every command resolves
+// them, and returns the effective value of every setting.
+func probeSettings(t *testing.T, config string, args ...string) (map[string]string, error) {
+	t.Helper()
+	values := map[string]string{}
+	probe := &cobra.Command{
+		Use:     "probe",
+		PreRunE: bindFlags,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, key := range settingKeys() {
+				values[key] = viper.GetString(key)
+			}
+			return nil
+		},
+	}
+	probe.Flags().String("fiat", defaultFiat, "")
+	probe.Flags().String("spreadsheet-name", defaultSpreadsheetName, "")
+	probe.Flags().Int64("start-row", defaultStartRow, "")
+	probe.Flags().String("start-column", defaultStartColumn, "")
+	err := executeRoot(t, config, []*cobra.Command{probe}, append([]string{"probe"}, args...)...)
+	return values, err
+}
+
+func TestSettingPrecedence(t *testing.T) {
+	setEnv(t, "CRYPTO_TRACKER_SPREADSHEET_NAME", "from env")
+	setEnv(t, "CRYPTO_TRACKER_START_ROW", "7")
+	values, err := probeSettings(t, `
+fiat: EUR
+spreadsheet-name: from file
+start-row: 5
+`, "--start-row", "9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		// flag over environment over config file
+		"start-row": "9",
+		// environment over config file
+		"spreadsheet-name": "from env",
+		// config file over default
+		"fiat": "EUR",
+		// default
+		"start-column": defaultStartColumn,
+		// the history costs a price per day, so it's opt-in
+		"history-sheet": "",
+	} {
+		if values[key] != want {
+			t.Errorf("%s = %q, want %q", key, values[key], want)
+		}
+	}
+}
+
+const profilesConfig = `
+fiat: USD
+spreadsheet-name: personal
+start-column: B
+profiles:
+  family:
+    fiat: EUR
+    spreadsheet-name: family
+    start-row: 4
+    start-column: C
+`
+
+func TestProfile(t *testing.T) {
+	setEnv(t, "CRYPTO_TRACKER_SPREADSHEET_NAME", "from env")
+	values, err := probeSettings(t, profilesConfig, "--profile", "family", "--start-row", "9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		// the profile overrides the top level settings
+		"fiat":         "EUR",
+		"start-column": "C",
+		// but not the environment or flags
+		"spreadsheet-name": "from env",
+		"start-row":        "9",
+	} {
+		if values[key] != want {
+			t.Errorf("%s = %q, want %q", key, values[key], want)
+		}
+	}
+}
+
+func TestProfileFromEnvironment(t *testing.T) {
+	setEnv(t, "CRYPTO_TRACKER_PROFILE", "family")
+	values, err := probeSettings(t, profilesConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["spreadsheet-name"] != "family" || values["start-row"] != "4" {
+		t.Errorf("spreadsheet-name = %q and start-row = %q, want the family profile's", values["spreadsheet-name"], values["start-row"])
+	}
+}
+
+func TestProfileWithoutSelection(t *testing.T) {
+	values, err := probeSettings(t, profilesConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["fiat"] != "USD" || values["spreadsheet-name"] != "personal" || values["start-row"] != "1" {
+		t.Errorf("settings = %v, want the top level ones", values)
+	}
+}
+
+func TestUnknownProfile(t *testing.T) {
+	_, err := probeSettings(t, profilesConfig, "--profile", "work")
+	if ExitCode(err) != ExitConfig || !strings.Contains(err.Error(), `profile "work" not found`) {
+		t.Errorf("err = %v (exit %d), want profile not found (exit %d)", err, ExitCode(err), ExitConfig)
+	}
+}
+
+func TestValidateCreateSpreadsheet(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := ioutil.WriteFile(file, []byte(reconcileCSV), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := "auth: adc\nfile: " + file + "\n"
+
+	// no spreadsheet yet, import creates one
+	if err := executeRoot(t, config+"create-spreadsheet: true\n", nil, "config", "validate"); err != nil {
+		t.Errorf("validate = %v, want no error", err)
+	}
+	err := executeRoot(t, config, nil, "config", "validate")
+	if ExitCode(err) != ExitConfig || !strings.Contains(err.Error(), "Missing spreadsheet-id") {
+		t.Errorf("validate = %v (exit %d), want a missing spreadsheet-id (exit %d)", err, ExitCode(err), ExitConfig)
+	}
+
+	if err := executeRoot(t, config+"create-spreadsheet: true\n", nil, "config", "set", "spreadsheet-id", ""); err != nil {
+		t.Errorf("set spreadsheet-id \"\" = %v, want no error", err)
+	}
+	if err := executeRoot(t, config, nil, "config", "set", "spreadsheet-id", ""); ExitCode(err) != ExitConfig {
+		t.Errorf("set spreadsheet-id \"\" = %v (exit %d), want exit %d", err, ExitCode(err), ExitConfig)
+	}
+}
+
+func TestConfigViewAndSet(t *testing.T) {
+	out, err := executeRootOutput(t, "fiat: EUR\n", nil, "config", "view")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "config file: ") {
+		t.Errorf("view printed %q, want the config file used", out)
+	}
+	found := false
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) > 2 && fields[0] == "fiat" {
+			found = fields[1] == "EUR" && fields[2] == "CRYPTO_TRACKER_FIAT"
+		}
+	}
+	if !found {
+		t.Errorf("view printed %q, want fiat EUR and its environment variable", out)
+	}
+
+	out, err = executeRootOutput(t, "", nil, "config", "set", "fiat", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "fiat set in ") {
+		t.Errorf("set printed %q, want where fiat was set", out)
+	}
+}
+
+func TestAccountIDs(t *testing.T) {
+	readConfig(t, "account-id: cro1a\nwallets: [cro1b, cro1a, cro1b, cro1c]\n")
+	if ids, want := accountIDs(), []string{"cro1a", "cro1b", "cro1c"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("accountIDs() = %q, want %q", ids, want)
+	}
+}
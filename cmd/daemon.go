@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/lib"
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultDaemonInterval = 15 * time.Minute
+	// how long an update already underway when the daemon is stopped gets to
+	// finish
+	daemonUpdateTimeout = time.Minute
+)
+
+func NewDaemonCommand() *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "daemon",
+		Short: "Keep the valuation in google sheets current and record portfolio snapshots",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := bindFlags(cmd, args); err != nil {
+				return err
+			}
+			return viper.BindPFlag("daemon-interval", cmd.Flags().Lookup("interval"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			importer, err := newImporter(cmd, false)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			prices := lib.NewCoinGeckoClient(defaultPriceServer)
+			return runDaemon(ctx, cmd.OutOrStdout(), importer, prices, viper.GetString("snapshot-sheet"), viper.GetDuration("daemon-interval"))
+		},
+	}
+
+	addSheetFlags(command)
+	command.Flags().Duration("interval", defaultDaemonInterval, "how often to refresh prices")
+	command.Flags().String("snapshot-sheet", tracker.DefaultSnapshotSheet, "name of the google sheet portfolio snapshots are appended to")
+	return command
+}
+
+// runDaemon revalues the table at the current price and appends a snapshot
+// every interval until ctx is done. An update that is underway when ctx is
+// done is finished first. Failed updates are retried on the next tick unless
+// google rejected the credentials.
+func runDaemon(ctx context.Context, out io.Writer, importer *tracker.TransactionImporter, prices tracker.PriceProvider, snapshotSheet string, interval time.Duration) error {
+	if interval <= 0 {
+		return configError(fmt.Errorf("daemon-interval must be positive, got %s", interval))
+	}
+	logf := timestampLogger(out)
+	logf("updating %s every %s", importer.SpreadsheetID, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		snapshot, err := updateValuation(importer, prices, snapshotSheet)
+		if err != nil {
+			err = googleError(err)
+			if ExitCode(err) == ExitAuth {
+				return err
+			}
+			logf("%v", err)
+		} else {
+			logf("CRO at %g: %.2f CRO worth %.2f, %+.2f on %.2f invested (%+.2f%%)",
+				snapshot.Price, snapshot.CRO, snapshot.Value, snapshot.Gain, snapshot.Invested, snapshot.Return*100)
+		}
+
+		select {
+		case <-ctx.Done():
+			logf("stopped updating %s", importer.SpreadsheetID)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateValuation runs a single update. It doesn't take the daemon's context,
+// so stopping the daemon doesn't leave the sheet half updated.
+func updateValuation(importer *tracker.TransactionImporter, prices tracker.PriceProvider, snapshotSheet string) (tracker.Snapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), daemonUpdateTimeout)
+	defer cancel()
+	roi, err := importer.Revalue(ctx, prices)
+	if err != nil {
+		return tracker.Snapshot{}, err
+	}
+	snapshot := tracker.Snapshot{Time: time.Now(), ROI: roi}
+	if err := importer.AppendSnapshot(ctx, snapshotSheet, snapshot); err != nil {
+		return tracker.Snapshot{}, err
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/igaskin/crypto-tracker/tracker/sheetstest"
+)
+
+// newDaemonImporter is an importer whose table holds a purchase.
+func newDaemonImporter(t *testing.T) (*sheetstest.Server, *tracker.TransactionImporter) {
+	t.Helper()
+	server, importer := newSheetImporter(t)
+	export := exportHeader + "2021-03-01 08:00:00,Recurring Buy,USD,-25,CRO,125,USD,25,25,recurring_buy_order\n"
+	if err := importer.Import(context.Background(), strings.NewReader(export), fixedPrice(0.1)); err != nil {
+		t.Fatal(err)
+	}
+	return server, importer
+}
+
+func TestRunDaemon(t *testing.T) {
+	server, importer := newDaemonImporter(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() {
+		done <- runDaemon(ctx, &out, importer, fixedPrice(0.4), "Snapshots", 5*time.Millisecond)
+	}()
+
+	// the header and two snapshots
+	deadline := time.Now().Add(5 * time.Second)
+	for len(server.Grid("sheet", "Snapshots")) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for snapshots, output:\n%s", out.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if got := server.Grid("sheet", tracker.DefaultPricesSheet)[1][2]; got != "0.4" {
+		t.Errorf("price = %s, want 0.4", got)
+	}
+	for _, row := range server.Grid("sheet", "Snapshots")[1:] {
+		if got, want := row[1:], []string{"125", "25", "0.4", "50", "25", "1"}; strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("snapshot = %q, want %q", got, want)
+		}
+	}
+	log := out.String()
+	for _, want := range []string{
+		"CRO at 0.4: 125.00 CRO worth 50.00, +25.00 on 25.00 invested (+100.00%)",
+		"stopped updating sheet",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log is missing %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestRunDaemonFinishesUpdateWhenStopped(t *testing.T) {
+	server, importer := newDaemonImporter(t)
+
+	// stopped before the first update even started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out syncBuffer
+	if err := runDaemon(ctx, &out, importer, fixedPrice(0.4), "Snapshots", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(server.Grid("sheet", "Snapshots")); got != 2 {
+		t.Errorf("snapshots tab has %d rows, want the header and one snapshot:\n%s", got, out.String())
+	}
+}
+
+func TestRunDaemonRejectsInterval(t *testing.T) {
+	err := runDaemon(context.Background(), &syncBuffer{}, nil, fixedPrice(0.4), "Snapshots", 0)
+	if code := ExitCode(err); code != ExitConfig {
+		t.Errorf("exit code = %d, want %d", code, ExitConfig)
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Exit codes of the crypto-tracker binary.
+const (
+	// ExitFailure covers usage mistakes and anything not classified below
+	ExitFailure = 1
+	// ExitConfig means a setting is missing or invalid
+	ExitConfig = 2
+	// ExitAuth means google rejected, or couldn't be given, credentials
+	ExitAuth = 3
+	// ExitNetwork means a remote API couldn't be reached or failed
+	ExitNetwork = 4
+	// ExitData means an input file couldn't be read or parsed
+	ExitData = 5
+)
+
+// exitError attaches an exit code to an error returned by a command.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	var existing *exitError
+	if errors.As(err, &existing) {
+		return err
+	}
+	return &exitError{code: code, err: err}
+}
+
+func configError(err error) error {
+	return withExitCode(ExitConfig, err)
+}
+
+func authError(err error) error {
+	return withExitCode(ExitAuth, err)
+}
+
+func networkError(err error) error {
+	return withExitCode(ExitNetwork, err)
+}
+
+func dataError(err error) error {
+	return withExitCode(ExitData, err)
+}
+
+// googleError classifies an error from a google API call, telling rejected
+// credentials apart from other failures.
+func googleError(err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && (apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusForbidden) {
+		return authError(err)
+	}
+	return networkError(err)
+}
+
+// ExitCode maps an error returned by a command to the process exit code.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return ExitFailure
+}
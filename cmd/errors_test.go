@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestExitCode(t *testing.T) {
+	failure := errors.New("failure")
+	for _, test := range []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "success", err: nil, code: 0},
+		{name: "unclassified", err: failure, code: ExitFailure},
+		{name: "config", err: configError(failure), code: ExitConfig},
+		{name: "auth", err: authError(failure), code: ExitAuth},
+		{name: "network", err: networkError(failure), code: ExitNetwork},
+		{name: "data", err: dataError(failure), code: ExitData},
+		{name: "wrapped", err: fmt.Errorf("context; %w", dataError(failure)), code: ExitData},
+		{name: "first class kept", err: networkError(configError(failure)), code: ExitConfig},
+		{name: "google unauthorized", err: googleError(&googleapi.Error{Code: http.StatusUnauthorized}), code: ExitAuth},
+		{name: "google forbidden", err: googleError(&googleapi.Error{Code: http.StatusForbidden}), code: ExitAuth},
+		{name: "google not found", err: googleError(&googleapi.Error{Code: http.StatusNotFound}), code: ExitNetwork},
+		{name: "google unreachable", err: googleError(failure), code: ExitNetwork},
+	} {
+		if code := ExitCode(test.err); code != test.code {
+			t.Errorf("%s: exit %d, want %d", test.name, code, test.code)
+		}
+	}
+}
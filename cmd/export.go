@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/igaskin/crypto-tracker/lib"
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const defaultExportFormat = "koinly"
+
+func NewExportCommand() *cobra.Command {
+	var command = &cobra.Command{
+		Use:     "export",
+		Short:   "Convert the csv export and on-chain rewards for Koinly or CoinTracker",
+		PreRunE: bindFlags,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := tracker.TaxFormatNamed(viper.GetString("format"))
+			if err != nil {
+				return configError(err)
+			}
+			exports, err := readExports()
+			if err != nil {
+				return dataError(fmt.Errorf("failed to read transactions; %w", err))
+			}
+			entries := tracker.TaxEntries(exports.transactions)
+			if accounts := accountIDs(); len(accounts) > 0 {
+				client := newExplorerClient(viper.GetString("explorer"))
+				for _, accountID := range accounts {
+					history, err := client.ListAccountTransactions(context.Background(), accountID)
+					if err != nil {
+						return networkError(fmt.Errorf("failed to get account transactions; %w", err))
+					}
+					rewards, err := chainRewards(history, accountID)
+					if err != nil {
+						return dataError(fmt.Errorf("failed to read rewards of %s; %w", accountID, err))
+					}
+					entries = append(entries, rewards...)
+				}
+				tracker.SortTaxEntries(entries)
+			}
+			return format.Write(cmd.OutOrStdout(), entries)
+		},
+	}
+	command.Flags().StringSliceP("file", "f", []string{defaultTransactionsFile}, "cyrpto transactions csv files or globs, merged")
+	command.Flags().String("format", defaultExportFormat, "format to convert to: koinly or cointracker")
+	command.Flags().StringP("account-id", "a", "", "cyrpto.org account id to include claimed staking rewards of")
+	command.Flags().String("explorer", defaultExplorer, "crypto.org explorer api url")
+	command.Flags().StringSlice("wallets", nil, "additional cyrpto.org account ids")
+	return command
+}
+
+// chainRewards are the staking rewards an account claimed on chain, each
+// with the fee of the claim when the account paid it.
+func chainRewards(history []lib.TransactionResult, address string) ([]tracker.TaxEntry, error) {
+	activity, err := lib.AccountActivity(history, address)
+	if err != nil {
+		return nil, err
+	}
+	var rewards []tracker.TaxEntry
+	for _, claim := range activity.Claims {
+		reward := tracker.TaxEntry{
+			Timestamp:        claim.Time,
+			ReceivedAmount:   claim.Amount,
+			ReceivedCurrency: "CRO",
+			Label:            tracker.StakingLabel,
+			Description:      "Staking rewards claimed on chain",
+			TxHash:           claim.Hash,
+		}
+		if claim.Fee != 0 {
+			reward.FeeAmount, reward.FeeCurrency = claim.Fee, "CRO"
+		}
+		rewards = append(rewards, reward)
+	}
+	return rewards, nil
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/lib"
+)
+
+func runExport(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := ioutil.WriteFile(file, []byte(reconcileCSV), 0600); err != nil {
+		t.Fatal(err)
+	}
+	command := NewExportCommand()
+	var out bytes.Buffer
+	command.SetOut(&out)
+	command.SetErr(ioutil.Discard)
+	command.SetArgs(append([]string{"--file", file}, args...))
+	err := command.Execute()
+	return out.String(), err
+}
+
+func TestExportCommand(t *testing.T) {
+	useExplorer(t, &lib.ExplorerClientInterfaceMock{
+		ListAccountTransactionsFunc: func(ctx context.Context, account string) ([]lib.TransactionResult, error) {
+			return []lib.TransactionResult{
+				{
+					Hash:      "CLAIM",
+					Blocktime: time.Date(2021, 5, 3, 12, 0, 0, 0, time.UTC),
+					Success:   true,
+					Feepayer:  account,
+					Fee:       []lib.Coin{{Denom: lib.BaseCRODenom, Amount: "5000"}},
+					Messages: []lib.Messages{{
+						Type: "/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward",
+						Content: lib.Content{
+							Delegatoraddress: account,
+							Amount:           lib.Coins{{Denom: lib.BaseCRODenom, Amount: "150000000"}},
+						},
+					}},
+				},
+				{
+					Hash:      "FAILED",
+					Blocktime: time.Date(2021, 5, 4, 12, 0, 0, 0, time.UTC),
+					Messages: []lib.Messages{{
+						Type: "/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward",
+						Content: lib.Content{
+							Delegatoraddress: account,
+							Amount:           lib.Coins{{Denom: lib.BaseCRODenom, Amount: "100000000"}},
+						},
+					}},
+				},
+			}, nil
+		},
+	})
+
+	out, err := runExport(t, "--account-id", reconcileAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Date,Sent Amount,Sent Currency,Received Amount,Received Currency,Fee Amount,Fee Currency,Net Worth Amount,Net Worth Currency,Label,Description,TxHash
+2021-04-30 08:00:00 UTC,50,USD,400,CRO,,,50,USD,,Recurring Buy,
+2021-05-01 09:00:00 UTC,200,CRO,,,,,25,USD,,Withdraw CRO,
+2021-05-03 12:00:00 UTC,,,1.5,CRO,0.00005,CRO,,,staking,Staking rewards claimed on chain,CLAIM
+2021-05-05 09:00:00 UTC,,,50,CRO,,,6,USD,,CRO Deposit,
+`
+	if out != want {
+		t.Errorf("export =\n%s\nwant\n%s", out, want)
+	}
+
+	out, err = runExport(t, "--format", "cointracker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "Date,Received Quantity,") || !strings.Contains(out, "04/30/2021 08:00:00,400,CRO,50,USD,,,\n") {
+		t.Errorf("cointracker export =\n%s", out)
+	}
+
+	_, err = runExport(t, "--format", "turbotax")
+	if ExitCode(err) != ExitConfig {
+		t.Errorf("err = %v (exit %d), want exit %d", err, ExitCode(err), ExitConfig)
+	}
+}
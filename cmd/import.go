@@ -2,623 +2,251 @@ package cmd
 
 import (
 	"context"
-	"encoding/csv"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"log"
-	"net/http"
 	"os"
-	"reflect"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 
 	"github.com/igaskin/crypto-tracker/lib"
+	"github.com/igaskin/crypto-tracker/tracker"
 	"github.com/spf13/cobra"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/googleapi"
+	"github.com/spf13/viper"
 	"google.golang.org/api/sheets/v4"
 )
 
+const (
+	defaultExplorer         = "https://crypto.org/explorer/api/v1/"
+	defaultPriceServer      = "https://api.coingecko.com/api/v3/"
+	defaultFiat             = "USD"
+	defaultSpreadsheetName  = "ROI"
+	defaultStartColumn      = "A"
+	defaultStartRow         = 1
+	defaultTransactionsFile = "crypto_transations.csv"
+)
+
+// newExplorerClient builds the explorer client commands talk to; tests swap
+// in a lib.ExplorerClientInterfaceMock.
+var newExplorerClient = func(server string) lib.ExplorerClientInterface {
+	return lib.NewExplorerClient(server)
+}
+
 func NewImportCommand() *cobra.Command {
-	var (
-		accountID              string
-		cryptoTransactionsFile string
-		fiat                   string
-		spreadsheetID          string
-		spreadSheetName        string
-	)
-	const (
-		defaultSpreadsheetName = "ROI"
-		defaultExplorer        = "https://crypto.org/explorer/api/v1/"
-	)
 	var command = &cobra.Command{
-		Use:   "import",
-		Short: "Import crypto transaction csv data into google sheets",
-		Run: func(cmd *cobra.Command, args []string) {
-			importer := NewTransactionImporter(TransactionImporterOpts{
-				Credentials:            "credentials.json",
-				SpreadsheetID:          spreadsheetID,
-				SheetName:              spreadSheetName,
-				CryptoTransactionsFile: cryptoTransactionsFile,
-				StartRow:               1,
-				StartColumn:            "A", // TODO(igaskin): fix bugs so that this can be something other than "A"
-				Fiat:                   fiat,
-			})
-			if err := importer.Validate(); err != nil {
-				log.Fatal(err)
+		Use:     "import",
+		Short:   "Import crypto transaction csv data into google sheets",
+		PreRunE: bindFlags,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			importer, err := newImporter(cmd, viper.GetBool("create-spreadsheet"))
+			if err != nil {
+				return err
 			}
-			if err := importer.parseTransations(); err != nil {
-				log.Fatalf("failed to import transactions; %v", err)
+			prices := lib.NewCoinGeckoClient(defaultPriceServer)
+			if viper.GetBool("watch") {
+				patterns := exportPatterns()
+				if len(patterns) != 1 {
+					return configError(fmt.Errorf("--watch takes a single file or directory, got %d", len(patterns)))
+				}
+				ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+				defer stop()
+				return watchImport(ctx, cmd.OutOrStdout(), importer, prices, patterns[0], viper.GetDuration("watch-interval"))
+			}
+			exports, err := readExports()
+			if err != nil {
+				return dataError(fmt.Errorf("failed to read transactions; %w", err))
+			}
+			if len(exports.paths) > 1 {
+				fmt.Fprintf(cmd.OutOrStdout(), "merged %d transactions from %d exports, skipped %d duplicates\n",
+					len(exports.transactions), len(exports.paths), exports.skipped)
 			}
-			if accountID != "" {
-				client := lib.NewExplorerClient(defaultExplorer)
-				ctx := context.Background()
-				resp, err := client.GetAccount(ctx, &lib.GetAccountOpts{
-					AccountID: accountID,
-				})
-				if err != nil {
-					log.Fatalf("failed to get account; %v", err)
+			ctx := context.Background()
+			if err := importer.ImportTransactions(ctx, exports.transactions, prices); err != nil {
+				return importError(fmt.Errorf("failed to import transactions; %w", err))
+			}
+			if historySheet := viper.GetString("history-sheet"); historySheet != "" {
+				if err := importer.PublishHistory(ctx, historySheet, exports.transactions, prices); err != nil {
+					return googleError(fmt.Errorf("failed to publish history; %w", err))
 				}
-				fmt.Printf("total balance: %s\nusable balance: %s\ntotal rewards: %s\n",
-					resp.Result.Totalbalance[0].Amount,
-					resp.Result.Balance[0].Amount,
-					resp.Result.Totalrewards[0].Amount,
-				)
 			}
+			client := newExplorerClient(viper.GetString("explorer"))
+			return printAccounts(ctx, cmd.OutOrStdout(), client, accountIDs())
 		},
 	}
 
-	// TODO(igaskin): read these in from the ~/.crypto-tracker config file
+	// every flag can also be set in ~/.crypto-tracker.yaml or as a
+	// CRYPTO_TRACKER_* environment variable, see `crypto-tracker config`
 
-	// the fiat type should be implied from the transactions file
-	command.Flags().StringVar(&fiat, "fiat", "USD", "type of fiat to use (USD or EUR")
-	command.Flags().StringVarP(&cryptoTransactionsFile, "file", "f", "crypto_transations.csv", "cyrpto transactions csv file")
-	command.Flags().StringVarP(&spreadsheetID, "spreadsheet-id", "s", "", "id of google sheet (found in the URL)")
-	command.Flags().StringVarP(&spreadSheetName, "spreadsheet-name", "n", defaultSpreadsheetName, "name of google sheet")
-	command.Flags().StringVarP(&accountID, "account-id", "a", "", "cyrpto.org account id")
+	addSheetFlags(command)
+	command.Flags().StringSliceP("file", "f", []string{defaultTransactionsFile}, "cyrpto transactions csv files or globs, merged into one import, or with --watch a directory of them")
+	command.Flags().Bool("watch", false, "keep running and import new transactions whenever the file changes")
+	command.Flags().Duration("watch-interval", defaultWatchInterval, "how often --watch checks the file for changes")
+	command.Flags().Bool("create-spreadsheet", false, "create a new spreadsheet when no spreadsheet-id is set")
+	command.Flags().String("history-sheet", "", fmt.Sprintf("name of the google sheet to write the daily history to, such as %q; skipped when empty", tracker.DefaultHistorySheet))
+	command.Flags().StringP("account-id", "a", "", "cyrpto.org account id")
+	command.Flags().String("explorer", defaultExplorer, "crypto.org explorer api url")
+	command.Flags().StringSlice("wallets", nil, "additional cyrpto.org account ids")
 	return command
 }
 
-type PurchaseEvent string
-type EarnEvent string
-
-func (p PurchaseEvent) String() string {
-	return string(p)
-}
-
-func (e EarnEvent) String() string {
-	return string(e)
-}
-
-const (
-	reoccurringBuy PurchaseEvent = "Recurring Buy"
-	usdToCRO       PurchaseEvent = "USD -> CRO"
-	eurToCRO       PurchaseEvent = "EUR -> CRO"
-	buyCRO         PurchaseEvent = "Buy CRO"
-	signupBonus    EarnEvent     = "Sign-up Bonus Unlocked"
-	cryptoEarn     EarnEvent     = "Crypto Earn"
-)
-
-func (t *TransactionImporter) Validate() error {
-	if t.SpreadsheetID == "" {
-		return errors.New("Missing spreadsheet-id")
-	}
-	return nil
-}
-
-func (t *TransactionImporter) getSheetID() error {
-	sheet, err := t.Googlesheet.Spreadsheets.Get(t.SpreadsheetID).Fields(googleapi.Field("sheets.properties")).Context(context.Background()).Do()
-	if err != nil {
-		return nil
-	}
-	for _, sheet := range sheet.Sheets {
-		if sheet.Properties.Title == t.sheetName {
-			t.sheetID = sheet.Properties.SheetId
+// printAccounts prints the balances of every account in CRO. Accounts that
+// never held anything have no balances at all.
+func printAccounts(ctx context.Context, out io.Writer, client lib.ExplorerClientInterface, accounts []string) error {
+	for _, accountID := range accounts {
+		resp, err := client.GetAccount(ctx, &lib.GetAccountOpts{
+			AccountID: accountID,
+		})
+		if err != nil {
+			return networkError(fmt.Errorf("failed to get account; %w", err))
 		}
+		var balances [3]float64
+		for i, coins := range [][]lib.Coin{resp.Result.Totalbalance, resp.Result.Balance, resp.Result.Totalrewards} {
+			if balances[i], err = lib.SumCRO(coins); err != nil {
+				return dataError(fmt.Errorf("invalid balance of %s; %w", accountID, err))
+			}
+		}
+		fmt.Fprintf(out, "account: %s\ntotal balance: %.8f CRO\nusable balance: %.8f CRO\ntotal rewards: %.8f CRO\n",
+			accountID, balances[0], balances[1], balances[2])
 	}
 	return nil
 }
 
-// TODO(igaskin): combine this with writeRow()
-func (t *TransactionImporter) writeRowData(rowData []interface{}) error {
-	var vr sheets.ValueRange
-	vr.Values = append(vr.Values, rowData)
-
-	rangez := fmt.Sprintf("%s!%s%d", t.sheetName, t.startColumn, t.currentRow)
-	t.currentRow += 1
-	_, err := t.Googlesheet.Spreadsheets.Values.Update(t.SpreadsheetID, rangez, &vr).ValueInputOption("USER_ENTERED").Do()
-	if err != nil {
-		return err
+// exportPatterns are the exports selected by the file setting, given as a
+// list, by repeating --file or separated by commas. Each may be a glob.
+func exportPatterns() []string {
+	value, ok := viper.Get("file").(string)
+	if !ok {
+		return viper.GetStringSlice("file")
+	}
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
 	}
-	return nil
+	return patterns
 }
 
-func (t *TransactionImporter) parseTransations() error {
-	// write the header
-	header := []interface{}{t.fiat, "CRO", "CRO Price", "Percent Change", fmt.Sprintf("%s Change", t.fiat)}
-	err := t.writeRowData(header)
-	if err != nil {
-		return err
-	}
-	for {
-		// TODO remove
-		// break
-		// Read each record from csv
-		record, err := t.CryptoTransactions.Read()
-		if err == io.EOF {
-			break
-		}
+// expandExports resolves the globs among patterns, each export once. A path
+// that isn't a glob is kept even when it doesn't exist, so opening it
+// reports why.
+func expandExports(patterns []string) ([]string, error) {
+	var paths []string
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
 		if err != nil {
-			log.Fatal(err)
+			return nil, configError(fmt.Errorf("invalid file pattern %q; %w", pattern, err))
 		}
-		switch record[1] {
-		case reoccurringBuy.String(), usdToCRO.String(), eurToCRO.String(), buyCRO.String():
-			if err := t.writeRow(record); err != nil {
-				return err
+		if len(matches) == 0 {
+			if strings.ContainsAny(pattern, "*?[") {
+				return nil, fmt.Errorf("no exports match %q", pattern)
+			}
+			matches = []string{pattern}
+		}
+		for _, path := range matches {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
 			}
-		case signupBonus.String(), cryptoEarn.String():
-			// TODO(igaskin): track these seperatly
-		default:
-			continue
 		}
 	}
-
-	// TODO(igaskin): need a more intelligent way to increment t.startColumn
-	// characters can be expressed as runes which are int32, which should be capable of aritmetic
-	footer := []interface{}{
-		fmt.Sprintf("=SUM(%s%d:%s%d)", t.startColumn, t.startRowIndex, t.startColumn, t.currentRow-1),
-		fmt.Sprintf("=SUM(%s%d:%s%d)", "B", t.startRowIndex, "B", t.currentRow-1),
-		fmt.Sprintf("=AVERAGE(%s%d:%s%d)", "C", t.startRowIndex, "C", t.currentRow-1),
-		fmt.Sprintf("=MINUS(DIVIDE(SUM(%[1]s%[3]d,%[2]s%[3]d), ABS(%[1]s%[3]d)),1)", "A", "E", t.currentRow),
-		fmt.Sprintf("=SUM(%s%d:%s%d)", "E", t.startRowIndex, "E", t.currentRow-1),
-	}
-	err = t.writeRowData(footer)
-	if err != nil {
-		return err
-	}
-
-	err = t.getSheetID()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// format data for readability
-	resp, err := t.Googlesheet.Spreadsheets.BatchUpdate(t.SpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
-		Requests: []*sheets.Request{
-			{
-				// format fiat as currency
-				RepeatCell: &sheets.RepeatCellRequest{
-					Range: &sheets.GridRange{
-						StartColumnIndex: t.startColumnIndex,
-						EndColumnIndex:   t.startColumnIndex + 1,
-						StartRowIndex:    t.startRowIndex - 1,
-						EndRowIndex:      t.currentRow,
-						SheetId:          t.sheetID,
-					},
-					Cell: &sheets.CellData{
-						UserEnteredFormat: &sheets.CellFormat{
-							NumberFormat: &sheets.NumberFormat{
-								Type: "CURRENCY",
-							},
-						},
-					},
-					Fields: "userEnteredFormat.numberFormat",
-				},
-			},
-			{
-				// format fiat as currency
-				RepeatCell: &sheets.RepeatCellRequest{
-					Range: &sheets.GridRange{
-						StartColumnIndex: t.startColumnIndex + 4,
-						EndColumnIndex:   t.startColumnIndex + 5,
-						StartRowIndex:    t.startRowIndex - 1,
-						EndRowIndex:      t.currentRow,
-						SheetId:          t.sheetID,
-					},
-					Cell: &sheets.CellData{
-						UserEnteredFormat: &sheets.CellFormat{
-							NumberFormat: &sheets.NumberFormat{
-								Type: "CURRENCY",
-							},
-						},
-					},
-					Fields: "userEnteredFormat.numberFormat",
-				},
-			},
-			{
-				// format cro purchase price as currency
-				RepeatCell: &sheets.RepeatCellRequest{
-					Range: &sheets.GridRange{
-						StartColumnIndex: t.startColumnIndex + 2,
-						EndColumnIndex:   t.startColumnIndex + 3,
-						StartRowIndex:    t.startRowIndex - 1,
-						EndRowIndex:      t.currentRow,
-						SheetId:          t.sheetID,
-					},
-					Cell: &sheets.CellData{
-						UserEnteredFormat: &sheets.CellFormat{
-							NumberFormat: &sheets.NumberFormat{
-								Type: "CURRENCY",
-							},
-						},
-					},
-					Fields: "userEnteredFormat.numberFormat",
-				},
-			},
-			{
-				// format CRO as a float
-				RepeatCell: &sheets.RepeatCellRequest{
-					Range: &sheets.GridRange{
-						StartColumnIndex: t.startColumnIndex + 1,
-						EndColumnIndex:   t.startColumnIndex + 2,
-						StartRowIndex:    t.startRowIndex - 1,
-						EndRowIndex:      t.currentRow,
-						SheetId:          t.sheetID,
-					},
-					Cell: &sheets.CellData{
-						UserEnteredFormat: &sheets.CellFormat{
-							NumberFormat: &sheets.NumberFormat{
-								Type:    "NUMBER",
-								Pattern: "#,##0.00",
-							},
-						},
-					},
-					Fields: "userEnteredFormat.numberFormat",
-				},
-			},
-			{
-				// format change as percentage
-				RepeatCell: &sheets.RepeatCellRequest{
-					Range: &sheets.GridRange{
-						StartColumnIndex: t.startColumnIndex + 3,
-						EndColumnIndex:   t.startColumnIndex + 4,
-						StartRowIndex:    t.startRowIndex - 1,
-						EndRowIndex:      t.currentRow,
-						SheetId:          t.sheetID,
-					},
-					Cell: &sheets.CellData{
-						UserEnteredFormat: &sheets.CellFormat{
-							NumberFormat: &sheets.NumberFormat{
-								Type:    "PERCENT",
-								Pattern: "#.0#%",
-							},
-						},
-					},
-					Fields: "userEnteredFormat.numberFormat",
-				},
-			},
-			{
-				// set font family
-				RepeatCell: &sheets.RepeatCellRequest{
-					Range: &sheets.GridRange{
-						StartColumnIndex: t.startColumnIndex,
-						EndColumnIndex:   t.startColumnIndex + 5,
-						StartRowIndex:    t.startRowIndex - 1,
-						EndRowIndex:      t.currentRow,
-						SheetId:          t.sheetID,
-					},
-					Cell: &sheets.CellData{
-						UserEnteredFormat: &sheets.CellFormat{
-							TextFormat: &sheets.TextFormat{
-								FontFamily: "Inconsolata",
-								FontSize:   11,
-							},
-						},
-					},
-					Fields: "userEnteredFormat.textFormat",
-				},
-			},
-			// clear any existing boarders
-			{
-				UpdateBorders: &sheets.UpdateBordersRequest{
-					Range: &sheets.GridRange{
-						StartColumnIndex: t.startColumnIndex,
-						EndColumnIndex:   t.startColumnIndex + 5,
-						StartRowIndex:    t.startRowIndex - 1,
-						EndRowIndex:      t.currentRow,
-						SheetId:          t.sheetID,
-					},
-					Top: &sheets.Border{
-						Style: "NONE",
-					},
-					InnerHorizontal: &sheets.Border{
-						Style: "NONE",
-					},
-					Bottom: &sheets.Border{
-						Style: "NONE",
-					},
-					InnerVertical: &sheets.Border{
-						Style: "NONE",
-					},
-					Left: &sheets.Border{
-						Style: "NONE",
-					},
-					Right: &sheets.Border{
-						Style: "NONE",
-					},
-				},
-			},
-			// add border to footer
-			{
-				UpdateBorders: &sheets.UpdateBordersRequest{
-					Range: &sheets.GridRange{
-						StartColumnIndex: t.startColumnIndex,
-						EndColumnIndex:   t.startColumnIndex + 5,
-						StartRowIndex:    t.currentRow - 2,
-						EndRowIndex:      t.currentRow - 1,
-						SheetId:          t.sheetID,
-					},
-					Top: &sheets.Border{
-						Style: "SOLID",
-						Color: &sheets.Color{
-							Red:   0,
-							Green: 0,
-							Blue:  0,
-						},
-					},
-				},
-			},
-			// add border to header
-			{
-				UpdateBorders: &sheets.UpdateBordersRequest{
-					Range: &sheets.GridRange{
-						StartColumnIndex: t.startColumnIndex,
-						EndColumnIndex:   t.startColumnIndex + 5,
-						StartRowIndex:    t.startRowIndex - 1,
-						EndRowIndex:      t.startRowIndex,
-						SheetId:          t.sheetID,
-					},
-					Bottom: &sheets.Border{
-						Style: "SOLID",
-						Color: &sheets.Color{
-							Red:   0,
-							Green: 0,
-							Blue:  0,
-						},
-					},
-				},
-			},
-			{
-				// bold the summary row
-				RepeatCell: &sheets.RepeatCellRequest{
-					Range: &sheets.GridRange{
-						StartColumnIndex: t.startColumnIndex,
-						EndColumnIndex:   t.startColumnIndex + 5,
-						StartRowIndex:    t.currentRow - 2,
-						EndRowIndex:      t.currentRow - 1,
-						SheetId:          t.sheetID,
-					},
-					Cell: &sheets.CellData{
-						UserEnteredFormat: &sheets.CellFormat{
-							TextFormat: &sheets.TextFormat{
-								Bold: true,
-							},
-						},
-					},
-					Fields: "userEnteredFormat.textFormat",
-				},
-			},
-			{
-				// conditional formatting gains/losses
-				AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
-					Index: 0,
-					Rule: &sheets.ConditionalFormatRule{
-						Ranges: []*sheets.GridRange{
-							{
-								StartColumnIndex: t.startColumnIndex + 3,
-								EndColumnIndex:   t.startColumnIndex + 5,
-								StartRowIndex:    t.startRowIndex - 1,
-								EndRowIndex:      t.currentRow,
-								SheetId:          t.sheetID,
-							},
-						},
-						BooleanRule: &sheets.BooleanRule{
-							Condition: &sheets.BooleanCondition{
-								Type: "NUMBER_GREATER",
-								Values: []*sheets.ConditionValue{
-									{
-										UserEnteredValue: "0",
-									},
-								},
-							},
-							Format: &sheets.CellFormat{
-								BackgroundColor: &sheets.Color{
-									Red:   0.850,
-									Green: 0.917,
-									Blue:  0.827,
-								},
-							},
-						},
-					},
-				},
-			},
-			{
-				// conditional formatting gains/losses
-				AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
-					Index: 0,
-					Rule: &sheets.ConditionalFormatRule{
-						Ranges: []*sheets.GridRange{
-							{
-								StartColumnIndex: t.startColumnIndex + 3,
-								EndColumnIndex:   t.startColumnIndex + 5,
-								StartRowIndex:    t.startRowIndex - 1,
-								EndRowIndex:      t.currentRow,
-								SheetId:          t.sheetID,
-							},
-						},
-						BooleanRule: &sheets.BooleanRule{
-							Condition: &sheets.BooleanCondition{
-								Type: "NUMBER_LESS_THAN_EQ",
-								Values: []*sheets.ConditionValue{
-									{
-										UserEnteredValue: "0",
-									},
-								},
-							},
-							Format: &sheets.CellFormat{
-								BackgroundColor: &sheets.Color{
-									Red:   0.956,
-									Green: 0.8,
-									Blue:  0.8,
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}).Context(context.Background()).Do()
-	if err != nil {
-		return err
+	if len(paths) == 0 {
+		return nil, configError(errors.New("Missing file"))
 	}
-	_ = resp
-	return nil
+	return paths, nil
 }
 
-type RowData struct {
-	Fiat string
-	CRO  string
-	// TODO: compute these from the other two values
-	PurchasePrice string
-	PercentChange string
-	FiatChange    string
-}
-
-func (r *RowData) ToSlice() []interface{} {
-	e := reflect.ValueOf(r).Elem()
-	s := make([]interface{}, e.NumField())
-	for i := 0; i < e.NumField(); i++ {
-		s[i] = e.Field(i).Interface()
-	}
-	return s
+// exports are the transactions of every export selected by the file
+// setting, merged oldest first.
+type exports struct {
+	paths        []string
+	transactions []tracker.Transaction
+	// skipped counts the transactions left out as duplicates
+	skipped int
 }
 
-// TODO(igaskin): be a bro and make a go-coingecko client
-func getCROPrice() float32 {
-	type p struct {
-		CryptoComChain struct {
-			Usd float32 `json:"usd"`
-		} `json:"crypto-com-chain"`
-	}
-
-	var price p
-
-	resp, err := http.Get("https://api.coingecko.com/api/v3/simple/price?ids=crypto-com-chain&vs_currencies=usd")
-	if err != nil {
-		// handle err
-		fmt.Println(err)
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
+func readExports() (*exports, error) {
+	paths, err := expandExports(exportPatterns())
 	if err != nil {
-		fmt.Println(err)
+		return nil, err
 	}
-	err = json.Unmarshal(body, &price)
+	transactions, skipped, err := tracker.ReadTransactionsFiles(paths...)
 	if err != nil {
-		fmt.Println(err)
+		return nil, err
 	}
-	return price.CryptoComChain.Usd
+	return &exports{paths: paths, transactions: transactions, skipped: skipped}, nil
 }
 
-var croCurrentPrice = getCROPrice()
-
-// TODO: refactor this to be part of the transaction importer struct
-func NewRowData(record []string, rowNumber int64) *RowData {
-	_, b, _, d, _, f, _, h, _, _ := record[0], record[1], record[2], record[3], record[4], record[5], record[6], record[7], record[8], record[9]
-
-	rowData := &RowData{
-		PurchasePrice: fmt.Sprintf("=(DIVIDE(A%[1]d,B%[1]d))", rowNumber),
-		PercentChange: fmt.Sprintf("=(DIVIDE(MINUS(%[2]f,C%[1]d),%[2]f))", rowNumber, croCurrentPrice),
-		FiatChange:    fmt.Sprintf("=MULTIPLY(A%[1]d,D%[1]d)", rowNumber),
-	}
-	switch b {
-	case reoccurringBuy.String():
-		rowData.Fiat, rowData.CRO = d, f
-	case usdToCRO.String(), eurToCRO.String():
-		rowData.Fiat, rowData.CRO = h, f
-	case buyCRO.String():
-		rowData.Fiat, rowData.CRO = h, d
-	case signupBonus.String(), cryptoEarn.String():
-	default:
-		return nil
-	}
-	return rowData
-}
-
-func (t *TransactionImporter) writeRow(record []string) error {
-	var err error
-	var vr sheets.ValueRange
-	vr.Values = append(vr.Values, NewRowData(record, t.currentRow).ToSlice())
-
-	rangez := fmt.Sprintf("%s!%s%d", t.sheetName, t.startColumn, t.currentRow)
-	t.currentRow += 1
-	_, err = t.Googlesheet.Spreadsheets.Values.Update(t.SpreadsheetID, rangez, &vr).ValueInputOption("USER_ENTERED").Do()
-	if err != nil {
-		return err
-	}
-	fmt.Println(record)
-	return nil
-}
-
-type TransactionImporter struct {
-	Googlesheet        *sheets.Service
-	SpreadsheetID      string
-	CryptoTransactions *csv.Reader
-	fiat               string
-	currentRow         int64
-	startRowIndex      int64
-	startColumn        string
-	startColumnIndex   int64
-	sheetName          string
-	sheetID            int64
-}
-
-type TransactionImporterOpts struct {
-	Credentials            string
-	SpreadsheetID          string
-	CryptoTransactionsFile string
-	StartRow               int64
-	StartColumn            string
-	SheetName              string
-	Fiat                   string
+// addSheetFlags adds the flags selecting the table in google sheets and how
+// to log in to google, shared by the commands that write to the sheet.
+func addSheetFlags(command *cobra.Command) {
+	// the fiat type should be implied from the transactions file
+	command.Flags().String("fiat", defaultFiat, "type of fiat to use (USD or EUR")
+	command.Flags().StringP("spreadsheet-id", "s", "", "id of google sheet (found in the URL)")
+	command.Flags().StringP("spreadsheet-name", "n", defaultSpreadsheetName, "name of google sheet")
+	command.Flags().Int64("start-row", defaultStartRow, "row of the top left cell of the table")
+	command.Flags().String("start-column", defaultStartColumn, "column of the top left cell of the table")
+	command.Flags().String("start-cell", "", "top left cell of the table in A1 notation, e.g. AA10 (overrides start-row and start-column)")
+	command.Flags().String("prices-sheet", tracker.DefaultPricesSheet, "name of the google sheet holding the prices the table is valued at")
+	command.Flags().String("auth", authOAuth, "how to authenticate with google: oauth, service-account or adc")
+	command.Flags().String("credentials", defaultCredentials, "path to the google oauth client secret file")
+	command.Flags().String("service-account-key", "", "path to a google service account json key (auth service-account)")
+	command.Flags().String("token", defaultToken, "path to the cached google oauth token")
+	command.Flags().String("token-store", tokenStoreFile, "how to store the oauth token: file or encrypted")
 }
 
-func NewTransactionImporter(opts TransactionImporterOpts) *TransactionImporter {
-	b, err := ioutil.ReadFile(opts.Credentials)
-	if err != nil {
-		log.Fatalf("Unable to read client secret file: %v", err)
-	}
-
-	config, err := google.ConfigFromJSON(b, "https://www.googleapis.com/auth/spreadsheets")
+// newImporter logs in to google and builds an importer for the table
+// selected by the sheet settings.
+func newImporter(cmd *cobra.Command, createSpreadsheet bool) (*tracker.TransactionImporter, error) {
+	startRow, startColumn := viper.GetInt64("start-row"), viper.GetString("start-column")
+	if startCell := viper.GetString("start-cell"); startCell != "" {
+		column, row, err := tracker.ParseCell(startCell)
+		if err != nil {
+			return nil, configError(err)
+		}
+		startRow, startColumn = row, tracker.ColumnName(column)
+	}
+	httpClient, err := googleClient(GoogleAuthOpts{
+		Method:            viper.GetString("auth"),
+		Credentials:       viper.GetString("credentials"),
+		TokenFile:         viper.GetString("token"),
+		TokenStore:        viper.GetString("token-store"),
+		ServiceAccountKey: viper.GetString("service-account-key"),
+		Out:               cmd.OutOrStdout(),
+		ErrOut:            cmd.ErrOrStderr(),
+	})
 	if err != nil {
-		log.Fatalf("Unable to parse client secret file to config: %v", err)
+		return nil, err
 	}
-	client := getClient(config)
-
-	googlesheet, err := sheets.New(client)
+	googlesheet, err := sheets.New(httpClient)
 	if err != nil {
-		log.Fatalf("Unable to retrieve Sheets client: %v", err)
-	}
-
-	csvfile, err := os.Open(opts.CryptoTransactionsFile)
+		return nil, fmt.Errorf("unable to retrieve Sheets client: %w", err)
+	}
+	importer, err := tracker.NewTransactionImporter(tracker.TransactionImporterOpts{
+		Googlesheet:       googlesheet,
+		SpreadsheetID:     viper.GetString("spreadsheet-id"),
+		SheetName:         viper.GetString("spreadsheet-name"),
+		StartRow:          startRow,
+		StartColumn:       startColumn,
+		Fiat:              viper.GetString("fiat"),
+		CreateSpreadsheet: createSpreadsheet,
+		PricesSheet:       viper.GetString("prices-sheet"),
+		Log:               cmd.OutOrStdout(),
+	})
 	if err != nil {
-		log.Fatalf("unable to open transactions file: %s", err)
+		return nil, configError(err)
 	}
-	cryptoTransactions := csv.NewReader(csvfile)
-	startColumnIndex := []rune(strings.ToUpper(opts.StartColumn))[0] - 65
+	return importer, nil
+}
 
-	return &TransactionImporter{
-		Googlesheet:        googlesheet,
-		SpreadsheetID:      opts.SpreadsheetID,
-		CryptoTransactions: cryptoTransactions,
-		fiat:               opts.Fiat,
-		currentRow:         opts.StartRow,
-		startRowIndex:      opts.StartRow,
-		startColumn:        opts.StartColumn,
-		startColumnIndex:   int64(startColumnIndex),
-		sheetName:          opts.SheetName,
+// importError classifies an error returned by the importer.
+func importError(err error) error {
+	var parseErr *tracker.ParseError
+	if errors.As(err, &parseErr) {
+		return dataError(err)
 	}
+	return googleError(err)
 }
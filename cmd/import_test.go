@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/igaskin/crypto-tracker/lib"
+)
+
+func TestPrintAccounts(t *testing.T) {
+	const emptyAccount = "cro1emptyaccount"
+	mock := &lib.ExplorerClientInterfaceMock{
+		GetAccountFunc: func(ctx context.Context, opts *lib.GetAccountOpts) (*lib.GetAccountResponse, error) {
+			if opts.AccountID == emptyAccount {
+				// the explorer lists no coins at all for a new account
+				return &lib.GetAccountResponse{Result: lib.Result{Address: opts.AccountID}}, nil
+			}
+			return &lib.GetAccountResponse{Result: lib.Result{
+				Address:      opts.AccountID,
+				Balance:      []lib.Coin{{Denom: lib.BaseCRODenom, Amount: "10000000000"}},
+				Totalbalance: []lib.Coin{{Denom: lib.BaseCRODenom, Amount: "25000000000"}},
+				Totalrewards: []lib.Coin{{Denom: lib.BaseCRODenom, Amount: "150000000"}},
+			}}, nil
+		},
+	}
+	var out bytes.Buffer
+	if err := printAccounts(context.Background(), &out, mock, []string{reconcileAccount, emptyAccount}); err != nil {
+		t.Fatal(err)
+	}
+	want := "account: " + reconcileAccount + "\n" +
+		"total balance: 250.00000000 CRO\nusable balance: 100.00000000 CRO\ntotal rewards: 1.50000000 CRO\n" +
+		"account: " + emptyAccount + "\n" +
+		"total balance: 0.00000000 CRO\nusable balance: 0.00000000 CRO\ntotal rewards: 0.00000000 CRO\n"
+	if out.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	mock.GetAccountFunc = func(ctx context.Context, opts *lib.GetAccountOpts) (*lib.GetAccountResponse, error) {
+		return nil, errors.New("connection refused")
+	}
+	if err := printAccounts(context.Background(), &out, mock, []string{reconcileAccount}); ExitCode(err) != ExitNetwork {
+		t.Errorf("err = %v (exit %d), want exit %d", err, ExitCode(err), ExitNetwork)
+	}
+}
@@ -1,14 +1,23 @@
 package cmd
 
 import (
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
@@ -17,88 +26,337 @@ import (
 )
 
 func NewLoginCommand() *cobra.Command {
+	var status bool
 	command := &cobra.Command{
-		Use:   "login",
-		Short: "Enable authentication to google sheets",
-		Run: func(cmd *cobra.Command, args []string) {
-			Run()
+		Use:     "login",
+		Short:   "Enable authentication to google sheets",
+		PreRunE: bindFlags,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if status {
+				store, err := newTokenStore(viper.GetString("token-store"), viper.GetString("token"))
+				if err != nil {
+					return configError(err)
+				}
+				printTokenStatus(cmd.OutOrStdout(), store)
+				return nil
+			}
+			return Run(GoogleAuthOpts{
+				Method:      viper.GetString("auth"),
+				Credentials: viper.GetString("credentials"),
+				TokenFile:   viper.GetString("token"),
+				TokenStore:  viper.GetString("token-store"),
+				Out:         cmd.OutOrStdout(),
+				ErrOut:      cmd.ErrOrStderr(),
+			})
 		},
 	}
+	command.Flags().BoolVar(&status, "status", false, "show the stored token instead of logging in")
+	command.Flags().String("credentials", defaultCredentials, "path to the google oauth client secret file")
+	command.Flags().String("token", defaultToken, "path to the cached google oauth token")
+	command.Flags().String("token-store", tokenStoreFile, "how to store the oauth token: file or encrypted")
+	command.Flags().Bool("no-browser", false, "print the login link and paste the redirect back, for headless machines")
 	return command
 }
 
+const sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+// supported ways of authenticating with google
+const (
+	// installed-app oauth as the user, interactive on first use
+	authOAuth = "oauth"
+	// a service account json key, for unattended imports
+	authServiceAccount = "service-account"
+	// application default credentials
+	authADC = "adc"
+)
+
+type GoogleAuthOpts struct {
+	Method            string
+	Credentials       string
+	TokenFile         string
+	TokenStore        string
+	ServiceAccountKey string
+	// Out receives the login prompts and ErrOut its notices, os.Stdout and
+	// os.Stderr when nil
+	Out    io.Writer
+	ErrOut io.Writer
+}
+
+func (opts GoogleAuthOpts) writers() (out, errOut io.Writer) {
+	out, errOut = opts.Out, opts.ErrOut
+	if out == nil {
+		out = os.Stdout
+	}
+	if errOut == nil {
+		errOut = os.Stderr
+	}
+	return out, errOut
+}
+
+// googleClient returns an http client authorized to edit spreadsheets.
+// Only the oauth method can prompt the user; the others never block.
+func googleClient(opts GoogleAuthOpts) (*http.Client, error) {
+	ctx := context.Background()
+	switch opts.Method {
+	case authOAuth, "":
+		b, err := ioutil.ReadFile(opts.Credentials)
+		if err != nil {
+			return nil, configError(fmt.Errorf("unable to read client secret file: %w", err))
+		}
+
+		// If modifying these scopes, delete your previously saved token file.
+		config, err := google.ConfigFromJSON(b, sheetsScope)
+		if err != nil {
+			return nil, configError(fmt.Errorf("unable to parse client secret file to config: %w", err))
+		}
+		store, err := newTokenStore(opts.TokenStore, opts.TokenFile)
+		if err != nil {
+			return nil, configError(err)
+		}
+		out, errOut := opts.writers()
+		return getClient(config, store, out, errOut)
+	case authServiceAccount:
+		if opts.ServiceAccountKey == "" {
+			return nil, configError(errors.New("Missing service-account-key"))
+		}
+		b, err := ioutil.ReadFile(opts.ServiceAccountKey)
+		if err != nil {
+			return nil, configError(fmt.Errorf("unable to read service account key: %w", err))
+		}
+		config, err := google.JWTConfigFromJSON(b, sheetsScope)
+		if err != nil {
+			return nil, configError(fmt.Errorf("unable to parse service account key: %w", err))
+		}
+		return config.Client(ctx), nil
+	case authADC:
+		client, err := google.DefaultClient(ctx, sheetsScope)
+		if err != nil {
+			return nil, authError(fmt.Errorf("unable to find application default credentials: %w", err))
+		}
+		return client, nil
+	default:
+		return nil, configError(fmt.Errorf("unknown auth method %q", opts.Method))
+	}
+}
+
 // Retrieve a token, saves the token, then returns the generated client.
-func getClient(config *oauth2.Config) *http.Client {
+func getClient(config *oauth2.Config, store TokenStore, out, errOut io.Writer) (*http.Client, error) {
 
-	// The file token.json stores the user's access and refresh tokens, and is
-	// created automatically when the authorization flow completes for the first
+	// The token store holds the user's access and refresh tokens, and is
+	// filled automatically when the authorization flow completes for the first
 	// time.
-	tokFile := "token.json"
-	tok, err := tokenFromFile(tokFile)
-	if err != nil {
-		tok = getTokenFromWeb(config)
-		saveToken(tokFile, tok)
+	tok, err := store.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		if tok, err = getTokenFromWeb(config, out, errOut); err != nil {
+			return nil, authError(err)
+		}
+		fmt.Fprintf(out, "Saving credential file to: %s\n", store.Location())
+		if err := store.Save(tok); err != nil {
+			return nil, authError(fmt.Errorf("unable to cache oauth token: %w", err))
+		}
+	} else if err != nil {
+		return nil, authError(fmt.Errorf("unable to load oauth token: %w", err))
 	}
-	return config.Client(context.Background(), tok)
+	return config.Client(context.Background(), tok), nil
 }
 
+// how long to wait for the browser to complete the authorization flow
+const loginTimeout = 5 * time.Minute
+
 // Request a token from the web, then returns the retrieved token.
-func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Printf("Go to the following link in your browser then type the "+
-		"authorization code: \n%v\n", authURL)
+//
+// The authorization code is delivered to a listener on the loopback interface
+// and protected by PKCE and a random state. With no-browser set, or when no
+// listener can be started, the user pastes the redirect URL back instead.
+func getTokenFromWeb(config *oauth2.Config, out, errOut io.Writer) (*oauth2.Token, error) {
+	conf := *config
+	state, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	authCodeURL := func() string {
+		return conf.AuthCodeURL(state, oauth2.AccessTypeOffline,
+			oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+			oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		)
+	}
 
 	var authCode string
-	if _, err := fmt.Scan(&authCode); err != nil {
-		log.Fatalf("Unable to read authorization code: %v", err)
+	listener, listenErr := net.Listen("tcp", "127.0.0.1:0")
+	if viper.GetBool("no-browser") || listenErr != nil {
+		if listenErr != nil {
+			fmt.Fprintf(errOut, "Unable to listen for the authorization redirect (%v), falling back to manual login\n", listenErr)
+		} else {
+			listener.Close()
+		}
+		conf.RedirectURL = "http://127.0.0.1/"
+		fmt.Fprintf(out, "Go to the following link in your browser, then paste the address of the "+
+			"page it redirects to (it will fail to load): \n%v\n", authCodeURL())
+		authCode, err = readAuthCode(os.Stdin, state)
+	} else {
+		defer listener.Close()
+		conf.RedirectURL = fmt.Sprintf("http://%s/", listener.Addr())
+		authURL := authCodeURL()
+		if err := openBrowser(authURL); err != nil {
+			fmt.Fprintf(out, "Go to the following link in your browser: \n%v\n", authURL)
+		} else {
+			fmt.Fprintf(out, "Your browser has been opened to authorize crypto-tracker, if it didn't "+
+				"open go to the following link: \n%v\n", authURL)
+		}
+		authCode, err = waitForAuthCode(listener, state, loginTimeout, errOut)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read authorization code: %w", err)
 	}
 
-	tok, err := config.Exchange(context.TODO(), authCode)
+	tok, err := conf.Exchange(context.TODO(), authCode, oauth2.SetAuthURLParam("code_verifier", verifier))
 	if err != nil {
-		log.Fatalf("Unable to retrieve token from web: %v", err)
+		return nil, fmt.Errorf("unable to retrieve token from web: %w", err)
 	}
-	return tok
+	return tok, nil
 }
 
-// Retrieves a token from a local file.
-func tokenFromFile(file string) (*oauth2.Token, error) {
-	f, err := os.Open(file)
+// randomToken returns 32 bytes of randomness encoded for use in a URL.
+func randomToken() (string, error) {
+	b, err := randomBytes(32)
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("unable to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// authCodeFromQuery extracts the authorization code from a redirect query.
+func authCodeFromQuery(query url.Values, state string) (string, error) {
+	if e := query.Get("error"); e != "" {
+		return "", fmt.Errorf("authorization failed: %s", e)
+	}
+	if query.Get("state") != state {
+		return "", errors.New("authorization state mismatch")
+	}
+	if query.Get("code") == "" {
+		return "", errors.New("missing authorization code")
+	}
+	return query.Get("code"), nil
+}
+
+// waitForAuthCode serves the loopback redirect until a request carrying the
+// login's state delivers a code or an error. Requests with any other state
+// are turned away without ending the login, and noted on errOut.
+func waitForAuthCode(listener net.Listener, state string, timeout time.Duration, errOut io.Writer) (string, error) {
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/" {
+				http.NotFound(w, r)
+				return
+			}
+			if r.URL.Query().Get("state") != state {
+				http.Error(w, "authorization state mismatch", http.StatusBadRequest)
+				fmt.Fprintln(errOut, "Turned away an authorization redirect that wasn't for this login")
+				return
+			}
+			code, err := authCodeFromQuery(r.URL.Query(), state)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				fmt.Fprintln(w, "crypto-tracker is authorized, you can close this window.")
+			}
+			select {
+			case results <- result{code, err}:
+			default:
+			}
+		}),
+	}
+	go server.Serve(listener)
+	// let the browser receive its response before the listener goes away
+	defer server.Shutdown(context.Background())
+
+	select {
+	case r := <-results:
+		return r.code, r.err
+	case <-time.After(timeout):
+		return "", fmt.Errorf("timed out after %s waiting for authorization", timeout)
 	}
-	defer f.Close()
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
-	return tok, err
 }
 
-// Saves a token to a file path.
-func saveToken(path string, token *oauth2.Token) {
-	fmt.Printf("Saving credential file to: %s\n", path)
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+// readAuthCode reads a pasted redirect URL. A bare authorization code is
+// refused, since without the URL its state can't be checked.
+func readAuthCode(in io.Reader, state string) (string, error) {
+	var input string
+	if _, err := fmt.Fscan(in, &input); err != nil {
+		return "", err
+	}
+	if !strings.Contains(input, "?") {
+		return "", errors.New("paste the whole address of the page, not just the code, so its state can be checked")
+	}
+	redirect, err := url.Parse(input)
 	if err != nil {
-		log.Fatalf("Unable to cache oauth token: %v", err)
+		return "", err
+	}
+	return authCodeFromQuery(redirect.Query(), state)
+}
+
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
 	}
-	defer f.Close()
-	json.NewEncoder(f).Encode(token)
+	return cmd.Start()
 }
 
-func Run() {
-	b, err := ioutil.ReadFile("credentials.json")
+// printTokenStatus describes the stored token without using it.
+func printTokenStatus(out io.Writer, store TokenStore) {
+	fmt.Fprintf(out, "token file: %s\n", store.Location())
+	tok, err := store.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintln(out, "status: logged out")
+		return
+	}
 	if err != nil {
-		log.Fatalf("Unable to read client secret file: %v", err)
+		fmt.Fprintf(out, "status: unreadable (%v)\n", err)
+		return
 	}
+	switch {
+	case tok.RefreshToken != "":
+		fmt.Fprintln(out, "status: logged in")
+	case tok.Valid():
+		fmt.Fprintln(out, "status: logged in until the access token expires (no refresh token)")
+	default:
+		fmt.Fprintln(out, "status: expired, run `crypto-tracker login` again")
+	}
+	if !tok.Expiry.IsZero() {
+		fmt.Fprintf(out, "access token expiry: %s\n", tok.Expiry.Local().Format(time.RFC1123))
+	}
+}
 
-	// If modifying these scopes, delete your previously saved token.json.
-	config, err := google.ConfigFromJSON(b, "https://www.googleapis.com/auth/spreadsheets")
+func Run(opts GoogleAuthOpts) error {
+	if opts.Method != authOAuth && opts.Method != "" {
+		out, _ := opts.writers()
+		fmt.Fprintf(out, "auth method %s doesn't need a login\n", opts.Method)
+		return nil
+	}
+	client, err := googleClient(opts)
 	if err != nil {
-		log.Fatalf("Unable to parse client secret file to config: %v", err)
+		return err
 	}
-	client := getClient(config)
 
 	_, err = sheets.New(client)
 	if err != nil {
-		log.Fatalf("Unable to retrieve Sheets client: %v", err)
+		return fmt.Errorf("unable to retrieve Sheets client: %w", err)
 	}
-
+	return nil
 }
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listen starts a loopback listener for the authorization redirect.
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	return listener
+}
+
+func TestWaitForAuthCode(t *testing.T) {
+	listener := listen(t)
+	type result struct {
+		code string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		code, err := waitForAuthCode(listener, "good-state", 5*time.Second, ioutil.Discard)
+		done <- result{code, err}
+	}()
+
+	redirect := func(query string) int {
+		t.Helper()
+		res, err := http.Get(fmt.Sprintf("http://%s/?%s", listener.Addr(), query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	// requests that didn't come from this login are turned away, and the
+	// login keeps waiting
+	for _, query := range []string{"code=forged&state=bad-state", "code=forged", "error=access_denied"} {
+		if status := redirect(query); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", query, status, http.StatusBadRequest)
+		}
+		select {
+		case r := <-done:
+			t.Fatalf("%s ended the login with %q, %v", query, r.code, r.err)
+		default:
+		}
+	}
+
+	if status := redirect("code=the-code&state=good-state"); status != http.StatusOK {
+		t.Errorf("status %d, want %d", status, http.StatusOK)
+	}
+	r := <-done
+	if r.err != nil || r.code != "the-code" {
+		t.Errorf("code = %q, err = %v, want the-code", r.code, r.err)
+	}
+}
+
+func TestWaitForAuthCodeDenied(t *testing.T) {
+	listener := listen(t)
+	done := make(chan error, 1)
+	go func() {
+		_, err := waitForAuthCode(listener, "good-state", 5*time.Second, ioutil.Discard)
+		done <- err
+	}()
+	res, err := http.Get(fmt.Sprintf("http://%s/?error=access_denied&state=good-state", listener.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "access_denied") {
+		t.Errorf("err = %v, want the denial", err)
+	}
+}
+
+func TestWaitForAuthCodeTimeout(t *testing.T) {
+	_, err := waitForAuthCode(listen(t), "good-state", 10*time.Millisecond, ioutil.Discard)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+}
+
+func TestReadAuthCode(t *testing.T) {
+	for _, test := range []struct {
+		pasted string
+		code   string
+		err    string
+	}{
+		{pasted: "http://127.0.0.1/?state=good-state&code=the-code", code: "the-code"},
+		{pasted: "http://127.0.0.1/?state=bad-state&code=the-code", err: "state mismatch"},
+		{pasted: "http://127.0.0.1/?state=good-state", err: "missing authorization code"},
+		{pasted: "the-code", err: "paste the whole address"},
+	} {
+		code, err := readAuthCode(strings.NewReader(test.pasted+"\n"), "good-state")
+		switch {
+		case test.err == "" && (err != nil || code != test.code):
+			t.Errorf("%s: code = %q, err = %v, want %q", test.pasted, code, err, test.code)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: err = %v, want %q", test.pasted, err, test.err)
+		}
+	}
+}
+
+// serviceAccountKey writes a service account json key whose tokens are
+// granted by a test server, and returns its path and the grant types the
+// server was asked for.
+func serviceAccountKey(t *testing.T) (string, *[]string) {
+	t.Helper()
+	var grants []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		grants = append(grants, r.FormValue("grant_type"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "service-account-token", "token_type": "Bearer", "expires_in": 3600}`)
+	}))
+	t.Cleanup(server.Close)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "crypto-tracker-test",
+		"private_key_id": "test-key",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "importer@crypto-tracker-test.iam.gserviceaccount.com",
+		"client_id":      "1",
+		"token_uri":      server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "service-account.json")
+	if err := ioutil.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, &grants
+}
+
+// authorization returns the Authorization header client sends.
+func authorization(t *testing.T, client *http.Client) string {
+	t.Helper()
+	var header string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+	}))
+	defer api.Close()
+	res, err := client.Get(api.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return header
+}
+
+const jwtBearerGrant = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+func TestGoogleClientServiceAccount(t *testing.T) {
+	key, grants := serviceAccountKey(t)
+	// the oauth client secret isn't needed
+	client, err := googleClient(GoogleAuthOpts{
+		Method:            authServiceAccount,
+		Credentials:       filepath.Join(t.TempDir(), "missing.json"),
+		ServiceAccountKey: key,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := authorization(t, client); header != "Bearer service-account-token" {
+		t.Errorf("Authorization = %q, want the service account's token", header)
+	}
+	if len(*grants) != 1 || (*grants)[0] != jwtBearerGrant {
+		t.Errorf("grants = %v, want a signed jwt exchanged", *grants)
+	}
+
+	_, err = googleClient(GoogleAuthOpts{Method: authServiceAccount})
+	if ExitCode(err) != ExitConfig || !strings.Contains(err.Error(), "Missing service-account-key") {
+		t.Errorf("err = %v (exit %d), want a missing key (exit %d)", err, ExitCode(err), ExitConfig)
+	}
+}
+
+func TestGoogleClientADC(t *testing.T) {
+	key, grants := serviceAccountKey(t)
+	setEnv(t, "GOOGLE_APPLICATION_CREDENTIALS", key)
+	client, err := googleClient(GoogleAuthOpts{Method: authADC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := authorization(t, client); header != "Bearer service-account-token" {
+		t.Errorf("Authorization = %q, want the default credentials' token", header)
+	}
+	if len(*grants) != 1 || (*grants)[0] != jwtBearerGrant {
+		t.Errorf("grants = %v, want a signed jwt exchanged", *grants)
+	}
+}
+
+func TestGoogleClientMissingCredentials(t *testing.T) {
+	_, err := googleClient(GoogleAuthOpts{
+		Method:      authOAuth,
+		Credentials: filepath.Join(t.TempDir(), "credentials.json"),
+		TokenFile:   filepath.Join(t.TempDir(), "token.json"),
+	})
+	if ExitCode(err) != ExitConfig || !strings.Contains(err.Error(), "unable to read client secret file") {
+		t.Errorf("err = %v (exit %d), want the client secret unreadable (exit %d)", err, ExitCode(err), ExitConfig)
+	}
+}
+
+func TestLoginStatus(t *testing.T) {
+	token := filepath.Join(t.TempDir(), "token.json")
+	out, err := executeRootOutput(t, "token: "+token+"\n", nil, "login", "--status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "token file: " + token + "\nstatus: logged out\n"; out != want {
+		t.Errorf("login --status printed %q, want %q", out, want)
+	}
+}
+
+func TestRunWithoutLogin(t *testing.T) {
+	var out bytes.Buffer
+	if err := Run(GoogleAuthOpts{Method: authADC, Out: &out}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "doesn't need a login") {
+		t.Errorf("printed %q, want the login skipped", out.String())
+	}
+}
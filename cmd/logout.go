@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+)
+
+const (
+	googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+	// where users remove the access of apps to their google account
+	googlePermissionsURL = "https://myaccount.google.com/permissions"
+)
+
+func NewLogoutCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:     "logout",
+		Short:   "Revoke and delete the stored google sheets token",
+		PreRunE: bindFlags,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := newTokenStore(viper.GetString("token-store"), viper.GetString("token"))
+			if err != nil {
+				return configError(err)
+			}
+			return logout(cmd.OutOrStdout(), store, googleRevokeURL)
+		},
+	}
+	command.Flags().String("token", defaultToken, "path to the cached google oauth token")
+	command.Flags().String("token-store", tokenStoreFile, "how the oauth token is stored: file or encrypted")
+	return command
+}
+
+// logout revokes the stored token at revokeURL and deletes it. The local copy
+// is deleted even when revoking fails, but the failure is returned so the
+// grant can be removed by hand from the google account's security settings.
+func logout(out io.Writer, store TokenStore, revokeURL string) error {
+	tok, err := store.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintln(out, "already logged out")
+		return nil
+	}
+	if err != nil {
+		// a token that can't be read can't be revoked, but is still removed
+		if err := store.Delete(); err != nil {
+			return fmt.Errorf("unable to delete token file: %w", err)
+		}
+		fmt.Fprintf(out, "Deleted %s\n", store.Location())
+		return authError(fmt.Errorf("unable to read token file, so it wasn't revoked, remove crypto-tracker's access at %s; %w", googlePermissionsURL, err))
+	}
+	revokeErr := revokeToken(revokeURL, tok)
+	if err := store.Delete(); err != nil {
+		return fmt.Errorf("unable to delete token file: %w", err)
+	}
+	fmt.Fprintf(out, "Deleted %s\n", store.Location())
+	if revokeErr != nil {
+		return networkError(fmt.Errorf("unable to revoke token, remove crypto-tracker's access at %s; %w", googlePermissionsURL, revokeErr))
+	}
+	fmt.Fprintln(out, "Revoked the token with google")
+	return nil
+}
+
+// revokeToken invalidates the grant behind the token with google. Revoking
+// the refresh token also revokes every access token issued from it.
+func revokeToken(revokeURL string, tok *oauth2.Token) error {
+	value := tok.RefreshToken
+	if value == "" {
+		value = tok.AccessToken
+	}
+	resp, err := http.PostForm(revokeURL, url.Values{"token": {value}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("google returned %s", resp.Status)
+	}
+	return nil
+}
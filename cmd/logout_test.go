@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// revokeServer answers revoke requests with status, recording the tokens.
+func revokeServer(t *testing.T, status int) (*httptest.Server, *[]string) {
+	t.Helper()
+	var revoked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		revoked = append(revoked, r.FormValue("token"))
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &revoked
+}
+
+// savedToken stores a token in a temporary file store.
+func savedToken(t *testing.T) TokenStore {
+	t.Helper()
+	store := &FileTokenStore{Path: filepath.Join(t.TempDir(), "token.json")}
+	if err := store.Save(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestLogout(t *testing.T) {
+	server, revoked := revokeServer(t, http.StatusOK)
+	store := savedToken(t)
+	var out bytes.Buffer
+	if err := logout(&out, store, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if len(*revoked) != 1 || (*revoked)[0] != "refresh" {
+		t.Errorf("revoked %v, want the refresh token", *revoked)
+	}
+	if _, err := store.Load(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("token still stored, err = %v", err)
+	}
+	if !strings.Contains(out.String(), "Revoked") {
+		t.Errorf("output %q doesn't report the revoke", out.String())
+	}
+}
+
+func TestLogoutRevokeFailed(t *testing.T) {
+	server, _ := revokeServer(t, http.StatusBadRequest)
+	store := savedToken(t)
+	var out bytes.Buffer
+	err := logout(&out, store, server.URL)
+	if ExitCode(err) != ExitNetwork || !strings.Contains(err.Error(), "400") {
+		t.Errorf("err = %v (exit %d), want the failed revoke (exit %d)", err, ExitCode(err), ExitNetwork)
+	}
+	if strings.Contains(out.String(), "Revoked") {
+		t.Errorf("output %q claims the token was revoked", out.String())
+	}
+	// the local copy is gone either way
+	if _, err := store.Load(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("token still stored, err = %v", err)
+	}
+}
+
+func TestLogoutWithoutToken(t *testing.T) {
+	server, revoked := revokeServer(t, http.StatusOK)
+	var out bytes.Buffer
+	store := &FileTokenStore{Path: filepath.Join(t.TempDir(), "token.json")}
+	if err := logout(&out, store, server.URL); err != nil || len(*revoked) != 0 {
+		t.Errorf("err = %v and revoked %v, want nothing done", err, *revoked)
+	}
+}
+
+func TestLogoutUnreadableToken(t *testing.T) {
+	server, revoked := revokeServer(t, http.StatusOK)
+	setEnv(t, "CRYPTO_TRACKER_TOKEN_PASSPHRASE", "correct horse")
+	store := &EncryptedTokenStore{Path: filepath.Join(t.TempDir(), "token.json")}
+	if err := store.Save(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}); err != nil {
+		t.Fatal(err)
+	}
+	setEnv(t, "CRYPTO_TRACKER_TOKEN_PASSPHRASE", "battery staple")
+
+	var out bytes.Buffer
+	err := logout(&out, store, server.URL)
+	if ExitCode(err) != ExitAuth || !strings.Contains(err.Error(), "wasn't revoked") || !strings.Contains(err.Error(), googlePermissionsURL) {
+		t.Errorf("err = %v (exit %d), want the skipped revoke reported (exit %d)", err, ExitCode(err), ExitAuth)
+	}
+	if len(*revoked) != 0 {
+		t.Errorf("revoked %v, want nothing sent", *revoked)
+	}
+	if _, err := os.Stat(store.Path); !os.IsNotExist(err) {
+		t.Errorf("token file still there, err = %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/lib"
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func NewReconcileCommand() *cobra.Command {
+	var tolerance float64
+	var command = &cobra.Command{
+		Use:     "reconcile",
+		Short:   "Compare CRO holdings derived from the csv export against on-chain balances",
+		PreRunE: bindFlags,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			accounts := accountIDs()
+			if len(accounts) == 0 {
+				return configError(errors.New("Missing account-id"))
+			}
+			if len(accounts) > 1 && viper.GetString("account-id") == "" {
+				return configError(fmt.Errorf("%d wallets configured; choose one with --account-id", len(accounts)))
+			}
+			accountID := accounts[0]
+			exports, err := readExports()
+			if err != nil {
+				return dataError(fmt.Errorf("failed to read transactions; %w", err))
+			}
+			transactions := exports.transactions
+			client := newExplorerClient(viper.GetString("explorer"))
+			ctx := context.Background()
+			account, err := client.GetAccount(ctx, &lib.GetAccountOpts{
+				AccountID: accountID,
+			})
+			if err != nil {
+				return networkError(fmt.Errorf("failed to get account; %w", err))
+			}
+			history, err := client.ListAccountTransactions(ctx, accountID)
+			if err != nil {
+				return networkError(fmt.Errorf("failed to get account transactions; %w", err))
+			}
+			r, err := reconcile(transactions, history, &account.Result, tolerance)
+			if err != nil {
+				return dataError(fmt.Errorf("failed to reconcile; %w", err))
+			}
+			r.print(cmd.OutOrStdout())
+			return nil
+		},
+	}
+	command.Flags().StringSliceP("file", "f", []string{defaultTransactionsFile}, "cyrpto transactions csv files or globs, merged")
+	command.Flags().StringP("account-id", "a", "", "cyrpto.org account id")
+	command.Flags().String("explorer", defaultExplorer, "crypto.org explorer api url")
+	command.Flags().Float64Var(&tolerance, "tolerance", 1, "maximum CRO difference when matching transfers (covers withdrawal fees)")
+	return command
+}
+
+const (
+	cryptoWithdrawal = "crypto_withdrawal"
+	cryptoDeposit    = "crypto_deposit"
+
+	// how far apart the app and the chain may timestamp the same transfer
+	transferWindow = 48 * time.Hour
+)
+
+// chainTransfer is a CRO movement into or out of the reconciled account.
+type chainTransfer struct {
+	lib.Transfer
+	matched bool
+}
+
+type kindTotal struct {
+	Kind  string
+	Rows  int
+	Delta float64
+}
+
+type reconciliation struct {
+	kinds []*kindTotal
+
+	csvWithdrawn float64
+	csvDeposited float64
+
+	received         float64
+	sent             float64
+	rewardsClaimed   float64
+	fees             float64
+	unclaimedRewards float64
+
+	expected float64
+	actual   float64
+
+	missingOnChain []tracker.Transaction
+	missingFromCSV []*chainTransfer
+	duplicates     []tracker.Transaction
+}
+
+func reconcile(transactions []tracker.Transaction, history []lib.TransactionResult, account *lib.Result, tolerance float64) (*reconciliation, error) {
+	r := &reconciliation{}
+
+	// CSV side: total the CRO delta of every transaction kind and flag rows
+	// that appear more than once
+	byKind := map[string]*kindTotal{}
+	seen := map[tracker.Transaction]bool{}
+	var transfers []tracker.Transaction
+	for _, t := range transactions {
+		delta := t.CRODelta()
+		if delta == 0 {
+			continue
+		}
+		total, ok := byKind[t.Kind]
+		if !ok {
+			total = &kindTotal{Kind: t.Kind}
+			byKind[t.Kind] = total
+			r.kinds = append(r.kinds, total)
+		}
+		total.Rows++
+		total.Delta += delta
+		if seen[t] {
+			r.duplicates = append(r.duplicates, t)
+		}
+		seen[t] = true
+
+		switch t.Kind {
+		case cryptoWithdrawal:
+			r.csvWithdrawn -= delta
+			transfers = append(transfers, t)
+		case cryptoDeposit:
+			r.csvDeposited += delta
+			transfers = append(transfers, t)
+		}
+	}
+	sort.Slice(r.kinds, func(i, j int) bool { return r.kinds[i].Kind < r.kinds[j].Kind })
+
+	// chain side
+	activity, err := lib.AccountActivity(history, account.Address)
+	if err != nil {
+		return nil, err
+	}
+	r.received, r.sent, r.rewardsClaimed, r.fees = activity.Received, activity.Sent, activity.RewardsClaimed, activity.Fees
+	var chain []*chainTransfer
+	for _, t := range activity.Transfers {
+		chain = append(chain, &chainTransfer{Transfer: t})
+	}
+	if r.unclaimedRewards, err = lib.SumCRO(account.Totalrewards); err != nil {
+		return nil, err
+	}
+	if r.actual, err = lib.SumCRO(account.Totalbalance); err != nil {
+		return nil, err
+	}
+	r.expected = r.received - r.sent - r.fees + r.rewardsClaimed + r.unclaimedRewards
+
+	// pair each app withdrawal with an on-chain receipt, and each app deposit
+	// with an on-chain send
+	for _, t := range transfers {
+		if match := findTransfer(chain, -t.CRODelta(), t.Timestamp, tolerance); match != nil {
+			match.matched = true
+			continue
+		}
+		r.missingOnChain = append(r.missingOnChain, t)
+	}
+	for _, c := range chain {
+		if !c.matched {
+			r.missingFromCSV = append(r.missingFromCSV, c)
+		}
+	}
+	return r, nil
+}
+
+func findTransfer(chain []*chainTransfer, amount float64, at time.Time, tolerance float64) *chainTransfer {
+	for _, c := range chain {
+		if c.matched || math.Signbit(c.Amount) != math.Signbit(amount) {
+			continue
+		}
+		if math.Abs(c.Amount-amount) > tolerance {
+			continue
+		}
+		if d := c.Time.Sub(at); d > transferWindow || d < -transferWindow {
+			continue
+		}
+		return c
+	}
+	return nil
+}
+
+func (r *reconciliation) print(out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "CSV TRANSACTION KIND\tROWS\tCRO\t")
+	var app float64
+	for _, k := range r.kinds {
+		fmt.Fprintf(w, "%s\t%d\t%.8f\t\n", k.Kind, k.Rows, k.Delta)
+		app += k.Delta
+	}
+	fmt.Fprintf(w, "app wallet balance\t\t%.8f\t\n", app)
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "TRANSFER\tCSV\tON-CHAIN\tDIFFERENCE\t")
+	fmt.Fprintf(w, "withdrawn from app\t%.8f\t%.8f\t%.8f\t\n", r.csvWithdrawn, r.received, r.received-r.csvWithdrawn)
+	fmt.Fprintf(w, "deposited to app\t%.8f\t%.8f\t%.8f\t\n", r.csvDeposited, r.sent, r.sent-r.csvDeposited)
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "ON-CHAIN\tCRO\t")
+	fmt.Fprintf(w, "received\t%.8f\t\n", r.received)
+	fmt.Fprintf(w, "sent\t%.8f\t\n", r.sent)
+	fmt.Fprintf(w, "fees\t%.8f\t\n", r.fees)
+	fmt.Fprintf(w, "rewards claimed\t%.8f\t\n", r.rewardsClaimed)
+	fmt.Fprintf(w, "rewards unclaimed\t%.8f\t\n", r.unclaimedRewards)
+	fmt.Fprintf(w, "expected balance\t%.8f\t\n", r.expected)
+	fmt.Fprintf(w, "explorer total balance\t%.8f\t\n", r.actual)
+	fmt.Fprintf(w, "difference\t%.8f\t\n", r.actual-r.expected)
+	w.Flush()
+
+	for _, t := range r.missingOnChain {
+		fmt.Fprintf(out, "missing on-chain: %s %s %.8f CRO\n", t.Timestamp.Format(tracker.TimestampLayout), t.Kind, t.CRODelta())
+	}
+	for _, c := range r.missingFromCSV {
+		fmt.Fprintf(out, "missing from csv: %s %s %.8f CRO\n", c.Time.UTC().Format(tracker.TimestampLayout), c.Hash, c.Amount)
+	}
+	for _, t := range r.duplicates {
+		fmt.Fprintf(out, "possible duplicate: %s %s %.8f CRO\n", t.Timestamp.Format(tracker.TimestampLayout), t.Kind, t.CRODelta())
+	}
+}
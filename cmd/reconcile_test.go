@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/lib"
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/spf13/viper"
+)
+
+const reconcileAccount = "cro1qx3y0ak0nl6anhe3dy0r7t5n7yqzmmg4mfyxwk"
+
+const reconcileCSV = `Timestamp (UTC),Transaction Description,Currency,Amount,To Currency,To Amount,Native Currency,Native Amount,Native Amount (in USD),Transaction Kind
+2021-04-30 08:00:00,Recurring Buy,USD,-50,CRO,400,USD,50,50,recurring_buy_order
+2021-05-01 09:00:00,Withdraw CRO,CRO,-200,,,USD,25,25,crypto_withdrawal
+2021-05-05 09:00:00,CRO Deposit,CRO,50,,,USD,6,6,crypto_deposit
+`
+
+// useExplorer points the commands at a mock explorer for the rest of the test.
+func useExplorer(t *testing.T, mock *lib.ExplorerClientInterfaceMock) {
+	original := newExplorerClient
+	newExplorerClient = func(server string) lib.ExplorerClientInterface {
+		return mock
+	}
+	t.Cleanup(func() {
+		newExplorerClient = original
+		viper.Reset()
+	})
+}
+
+func runReconcile(t *testing.T) (string, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := ioutil.WriteFile(file, []byte(reconcileCSV), 0600); err != nil {
+		t.Fatal(err)
+	}
+	command := NewReconcileCommand()
+	var out bytes.Buffer
+	command.SetOut(&out)
+	command.SetErr(ioutil.Discard)
+	command.SetArgs([]string{"--file", file, "--account-id", reconcileAccount})
+	err := command.Execute()
+	return out.String(), err
+}
+
+func TestReconcileCommand(t *testing.T) {
+	mock := &lib.ExplorerClientInterfaceMock{
+		GetAccountFunc: func(ctx context.Context, opts *lib.GetAccountOpts) (*lib.GetAccountResponse, error) {
+			return &lib.GetAccountResponse{Result: lib.Result{
+				Address:      opts.AccountID,
+				Totalrewards: []lib.Coin{{Denom: lib.BaseCRODenom, Amount: "50000000"}},
+				Totalbalance: []lib.Coin{{Denom: lib.BaseCRODenom, Amount: "19999995000"}},
+			}}, nil
+		},
+		ListAccountTransactionsFunc: func(ctx context.Context, account string) ([]lib.TransactionResult, error) {
+			return []lib.TransactionResult{
+				{
+					Hash:      "SEND",
+					Blocktime: time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC),
+					Success:   true,
+					Messages: []lib.Messages{{
+						Type: "/cosmos.bank.v1beta1.MsgSend",
+						Content: lib.Content{
+							Fromaddress: "cro1app",
+							Toaddress:   account,
+							Amount:      lib.Coins{{Denom: lib.BaseCRODenom, Amount: "19950000000"}},
+						},
+					}},
+				},
+				{
+					Hash:      "DELEGATE",
+					Blocktime: time.Date(2021, 5, 2, 12, 0, 0, 0, time.UTC),
+					Success:   true,
+					Feepayer:  account,
+					Fee:       []lib.Coin{{Denom: lib.BaseCRODenom, Amount: "5000"}},
+					Messages: []lib.Messages{{
+						Type: "/cosmos.staking.v1beta1.MsgDelegate",
+						Content: lib.Content{
+							Delegatoraddress: account,
+							Amount:           lib.Coins{{Denom: lib.BaseCRODenom, Amount: "10000000000"}},
+						},
+					}},
+				},
+			}, nil
+		},
+	}
+	useExplorer(t, mock)
+
+	out, err := runReconcile(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"withdrawn from app  200.00000000  199.50000000  -0.50000000",
+		"fees                    0.00005000",
+		"expected balance        199.99995000",
+		"difference              0.00000000",
+		"missing on-chain: 2021-05-05 09:00:00 crypto_deposit 50.00000000 CRO",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "missing from csv") {
+		t.Errorf("the withdrawal wasn't paired with its on-chain receipt:\n%s", out)
+	}
+	if calls := mock.GetAccountCalls(); len(calls) != 1 || calls[0].Opts.AccountID != reconcileAccount {
+		t.Errorf("GetAccount calls = %+v", calls)
+	}
+	if calls := mock.ListAccountTransactionsCalls(); len(calls) != 1 || calls[0].Account != reconcileAccount {
+		t.Errorf("ListAccountTransactions calls = %+v", calls)
+	}
+}
+
+func TestReconcileCommandExplorerDown(t *testing.T) {
+	useExplorer(t, &lib.ExplorerClientInterfaceMock{
+		GetAccountFunc: func(ctx context.Context, opts *lib.GetAccountOpts) (*lib.GetAccountResponse, error) {
+			return nil, &lib.ExplorerError{StatusCode: 502, Status: "502 Bad Gateway"}
+		},
+	})
+
+	_, err := runReconcile(t)
+	var explorerErr *lib.ExplorerError
+	if !errors.As(err, &explorerErr) {
+		t.Fatalf("err = %v, want an ExplorerError", err)
+	}
+	if code := ExitCode(err); code != ExitNetwork {
+		t.Errorf("exit code = %d, want %d", code, ExitNetwork)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	transactions, err := tracker.ParseTransactions(strings.NewReader(reconcileCSV +
+		"2021-05-05 09:00:00,CRO Deposit,CRO,50,,,USD,6,6,crypto_deposit\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cro := func(amount string) lib.Coins {
+		return lib.Coins{{Denom: lib.BaseCRODenom, Amount: amount}}
+	}
+	history := []lib.TransactionResult{
+		{
+			Hash: "RECEIVE", Blocktime: time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC), Success: true,
+			Messages: []lib.Messages{{Type: "/cosmos.bank.v1beta1.MsgSend", Content: lib.Content{
+				Fromaddress: "cro1app", Toaddress: reconcileAccount, Amount: cro("19990000000"),
+			}}},
+		},
+		{
+			Hash: "CLAIM", Blocktime: time.Date(2021, 5, 3, 10, 0, 0, 0, time.UTC), Success: true,
+			Feepayer: reconcileAccount, Fee: cro("5000"),
+			Messages: []lib.Messages{{Type: "/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward", Content: lib.Content{
+				Delegatoraddress: reconcileAccount, Amount: cro("100000000"),
+			}}},
+		},
+		{
+			Hash: "FAILED", Blocktime: time.Date(2021, 5, 4, 10, 0, 0, 0, time.UTC),
+			Messages: []lib.Messages{{Type: "/cosmos.bank.v1beta1.MsgSend", Content: lib.Content{
+				Fromaddress: "cro1app", Toaddress: reconcileAccount, Amount: cro("100000000000"),
+			}}},
+		},
+		{
+			Hash: "STRAY", Blocktime: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC), Success: true,
+			Messages: []lib.Messages{{Type: "/cosmos.bank.v1beta1.MsgSend", Content: lib.Content{
+				Fromaddress: "cro1other", Toaddress: reconcileAccount, Amount: cro("1000000000"),
+			}}},
+		},
+	}
+	account := &lib.Result{
+		Address:      reconcileAccount,
+		Totalrewards: cro("20000000"),
+		Totalbalance: cro("21009995000"),
+	}
+
+	r, err := reconcile(transactions, history, account, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, got := range map[string][2]float64{
+		"csv withdrawn":  {r.csvWithdrawn, 200},
+		"csv deposited":  {r.csvDeposited, 100},
+		"received":       {r.received, 209.9},
+		"sent":           {r.sent, 0},
+		"claimed":        {r.rewardsClaimed, 1},
+		"fees":           {r.fees, 0.00005},
+		"unclaimed":      {r.unclaimedRewards, 0.2},
+		"expected":       {r.expected, 211.09995},
+		"actual balance": {r.actual, 210.09995},
+	} {
+		if math.Abs(got[0]-got[1]) > 1e-9 {
+			t.Errorf("%s = %.8f, want %.8f", name, got[0], got[1])
+		}
+	}
+	// the withdrawal is paired with the receipt 0.1 CRO short, within
+	// tolerance; both deposits have no on-chain send
+	if len(r.missingOnChain) != 2 || r.missingOnChain[0].Kind != cryptoDeposit {
+		t.Errorf("missing on-chain = %+v, want the two deposits", r.missingOnChain)
+	}
+	if len(r.missingFromCSV) != 1 || r.missingFromCSV[0].Hash != "STRAY" {
+		t.Errorf("missing from csv = %+v, want STRAY", r.missingFromCSV)
+	}
+	if len(r.duplicates) != 1 || r.duplicates[0].Kind != cryptoDeposit {
+		t.Errorf("duplicates = %+v, want the repeated deposit", r.duplicates)
+	}
+}
+
+func TestFindTransfer(t *testing.T) {
+	at := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name     string
+		transfer chainTransfer
+		amount   float64
+		found    bool
+	}{
+		{"exact", chainTransfer{Transfer: lib.Transfer{Time: at, Amount: 200}}, 200, true},
+		{"within tolerance", chainTransfer{Transfer: lib.Transfer{Time: at, Amount: 199.5}}, 200, true},
+		{"beyond tolerance", chainTransfer{Transfer: lib.Transfer{Time: at, Amount: 198}}, 200, false},
+		{"opposite direction", chainTransfer{Transfer: lib.Transfer{Time: at, Amount: -200}}, 200, false},
+		{"within window", chainTransfer{Transfer: lib.Transfer{Time: at.Add(transferWindow), Amount: 200}}, 200, true},
+		{"outside window", chainTransfer{Transfer: lib.Transfer{Time: at.Add(-transferWindow - time.Second), Amount: 200}}, 200, false},
+		{"already matched", chainTransfer{Transfer: lib.Transfer{Time: at, Amount: 200}, matched: true}, 200, false},
+	} {
+		transfer := test.transfer
+		if found := findTransfer([]*chainTransfer{&transfer}, test.amount, at, 1) != nil; found != test.found {
+			t.Errorf("%s: found = %v, want %v", test.name, found, test.found)
+		}
+	}
+}
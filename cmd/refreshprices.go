@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/igaskin/crypto-tracker/lib"
+	"github.com/spf13/cobra"
+)
+
+func NewRefreshPricesCommand() *cobra.Command {
+	var command = &cobra.Command{
+		Use:     "refresh-prices",
+		Short:   "Update the prices tab, revaluing every table in the spreadsheet",
+		PreRunE: bindFlags,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			importer, err := newImporter(cmd, false)
+			if err != nil {
+				return err
+			}
+			quotes, err := importer.RefreshPrices(context.Background(), lib.NewCoinGeckoClient(defaultPriceServer))
+			if err != nil {
+				return googleError(err)
+			}
+			if len(quotes) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "no prices to refresh, import transactions first")
+				return nil
+			}
+			for _, quote := range quotes {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: %g %s\n", quote.Asset, quote.Price, quote.Fiat)
+			}
+			return nil
+		},
+	}
+
+	addSheetFlags(command)
+	return command
+}
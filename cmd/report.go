@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/lib"
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// report output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputCSV   = "csv"
+)
+
+// newPriceClient builds the price client commands value holdings with; tests
+// swap in a fixed price.
+var newPriceClient = func(server string) tracker.PriceProvider {
+	return lib.NewCoinGeckoClient(server)
+}
+
+func NewReportCommand() *cobra.Command {
+	var command = &cobra.Command{
+		Use:     "report",
+		Short:   "Print holdings, returns and income from the csv export",
+		PreRunE: bindFlags,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := viper.GetString("output")
+			if err := validateSetting("output", output); err != nil {
+				return configError(err)
+			}
+			exports, err := readExports()
+			if err != nil {
+				return dataError(fmt.Errorf("failed to read transactions; %w", err))
+			}
+			transactions := exports.transactions
+			ctx := context.Background()
+			r, err := newReport(ctx, transactions, newPriceClient(defaultPriceServer), viper.GetString("fiat"), time.Now())
+			if err != nil {
+				return err
+			}
+			for _, err := range r.priceErrors {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+			}
+			if accounts := accountIDs(); len(accounts) > 0 {
+				client := newExplorerClient(viper.GetString("explorer"))
+				for _, accountID := range accounts {
+					if err := r.addAccount(ctx, client, accountID); err != nil {
+						return err
+					}
+				}
+			}
+			return r.write(cmd.OutOrStdout(), output)
+		},
+	}
+	command.Flags().StringSliceP("file", "f", []string{defaultTransactionsFile}, "cyrpto transactions csv files or globs, merged")
+	command.Flags().String("fiat", defaultFiat, "type of fiat to use (USD or EUR")
+	command.Flags().StringP("output", "o", outputTable, "output format: table, json, yaml or csv")
+	command.Flags().StringP("account-id", "a", "", "cyrpto.org account id to include on-chain balances and staking rewards for")
+	command.Flags().String("explorer", defaultExplorer, "crypto.org explorer api url")
+	command.Flags().StringSlice("wallets", nil, "additional cyrpto.org account ids")
+	return command
+}
+
+type report struct {
+	Time     time.Time       `json:"time" yaml:"time"`
+	Fiat     string          `json:"fiat" yaml:"fiat"`
+	Holdings []reportHolding `json:"holdings" yaml:"holdings"`
+	Returns  reportReturns   `json:"returns" yaml:"returns"`
+	Income   []reportIncome  `json:"income" yaml:"income"`
+	Accounts []reportAccount `json:"accounts,omitempty" yaml:"accounts,omitempty"`
+
+	croPrice float64
+	// why holdings are missing a price
+	priceErrors []error
+}
+
+// reportHolding is an asset held by the app wallet. Price and value are
+// missing for assets without a price.
+type reportHolding struct {
+	Asset  string   `json:"asset" yaml:"asset"`
+	Amount float64  `json:"amount" yaml:"amount"`
+	Price  *float64 `json:"price" yaml:"price"`
+	Value  *float64 `json:"value" yaml:"value"`
+}
+
+// reportReturns is the return on the CRO purchases. XIRR and TWR are
+// missing when there are no purchases to compute them from.
+type reportReturns struct {
+	CostBasis    float64  `json:"cost_basis" yaml:"cost_basis"`
+	CRO          float64  `json:"cro" yaml:"cro"`
+	AveragePrice float64  `json:"average_price" yaml:"average_price"`
+	Price        float64  `json:"price" yaml:"price"`
+	Value        float64  `json:"value" yaml:"value"`
+	Gain         float64  `json:"gain" yaml:"gain"`
+	TotalReturn  float64  `json:"total_return" yaml:"total_return"`
+	XIRR         *float64 `json:"xirr" yaml:"xirr"`
+	TWR          *float64 `json:"twr" yaml:"twr"`
+}
+
+// reportIncome is the CRO paid out by an income source, valued when it was
+// paid and now.
+type reportIncome struct {
+	Source    string  `json:"source" yaml:"source"`
+	Payouts   int     `json:"payouts" yaml:"payouts"`
+	CRO       float64 `json:"cro" yaml:"cro"`
+	PaidValue float64 `json:"paid_value" yaml:"paid_value"`
+	Value     float64 `json:"value" yaml:"value"`
+}
+
+// reportAccount is the on-chain balance and staking rewards of an account.
+type reportAccount struct {
+	Account           string  `json:"account" yaml:"account"`
+	Balance           float64 `json:"balance" yaml:"balance"`
+	TotalBalance      float64 `json:"total_balance" yaml:"total_balance"`
+	RewardsClaimed    float64 `json:"rewards_claimed" yaml:"rewards_claimed"`
+	RewardsUnclaimed  float64 `json:"rewards_unclaimed" yaml:"rewards_unclaimed"`
+	TotalBalanceValue float64 `json:"total_balance_value" yaml:"total_balance_value"`
+}
+
+func newReport(ctx context.Context, transactions []tracker.Transaction, prices tracker.PriceProvider, fiat string, at time.Time) (*report, error) {
+	price, err := prices.Price(ctx, "CRO", fiat)
+	if err != nil {
+		return nil, networkError(fmt.Errorf("failed to get CRO price; %w", err))
+	}
+	r := &report{Time: at.UTC(), Fiat: fiat, croPrice: price}
+
+	for _, h := range tracker.Holdings(transactions) {
+		holding := reportHolding{Asset: h.Asset, Amount: h.Amount}
+		// assets without a price are listed without a value
+		if price, err := prices.Price(ctx, h.Asset, fiat); err == nil {
+			value := h.Amount * price
+			holding.Price, holding.Value = &price, &value
+		} else {
+			r.priceErrors = append(r.priceErrors, fmt.Errorf("no price for %s, listed without a value; %w", h.Asset, err))
+		}
+		r.Holdings = append(r.Holdings, holding)
+	}
+
+	purchases := tracker.Purchases(transactions, fiat)
+	roi := tracker.ComputeROI(purchases, price)
+	r.Returns = reportReturns{
+		CostBasis:    roi.Invested,
+		CRO:          roi.CRO,
+		AveragePrice: roi.AveragePrice,
+		Price:        roi.Price,
+		Value:        roi.Value,
+		Gain:         roi.Gain,
+		TotalReturn:  roi.Return,
+	}
+	if returns, err := tracker.ComputeReturns(purchases, price, at); err == nil {
+		r.Returns.XIRR, r.Returns.TWR = &returns.XIRR, &returns.TWR
+	}
+
+	for _, income := range tracker.IncomeBySource(transactions) {
+		r.Income = append(r.Income, reportIncome{
+			Source:    income.Source,
+			Payouts:   income.Payouts,
+			CRO:       income.CRO,
+			PaidValue: income.Fiat,
+			Value:     income.CRO * price,
+		})
+	}
+	return r, nil
+}
+
+// addAccount adds the on-chain balance and staking rewards of an account.
+func (r *report) addAccount(ctx context.Context, client lib.ExplorerClientInterface, accountID string) error {
+	resp, err := client.GetAccount(ctx, &lib.GetAccountOpts{
+		AccountID: accountID,
+	})
+	if err != nil {
+		return networkError(fmt.Errorf("failed to get account; %w", err))
+	}
+	history, err := client.ListAccountTransactions(ctx, accountID)
+	if err != nil {
+		return networkError(fmt.Errorf("failed to get account transactions; %w", err))
+	}
+	activity, err := lib.AccountActivity(history, resp.Result.Address)
+	if err != nil {
+		return dataError(fmt.Errorf("failed to read account transactions; %w", err))
+	}
+	account := reportAccount{Account: accountID, RewardsClaimed: activity.RewardsClaimed}
+	if account.Balance, err = lib.SumCRO(resp.Result.Balance); err != nil {
+		return dataError(fmt.Errorf("failed to read account balance; %w", err))
+	}
+	if account.TotalBalance, err = lib.SumCRO(resp.Result.Totalbalance); err != nil {
+		return dataError(fmt.Errorf("failed to read account balance; %w", err))
+	}
+	if account.RewardsUnclaimed, err = lib.SumCRO(resp.Result.Totalrewards); err != nil {
+		return dataError(fmt.Errorf("failed to read account rewards; %w", err))
+	}
+	account.TotalBalanceValue = account.TotalBalance * r.croPrice
+	r.Accounts = append(r.Accounts, account)
+	return nil
+}
+
+func (r *report) write(out io.Writer, format string) error {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case outputYAML:
+		data, err := yaml.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	case outputCSV:
+		return r.writeCSV(out)
+	default:
+		return r.print(out)
+	}
+}
+
+// print writes the report as aligned tables.
+func (r *report) print(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ASSET\tAMOUNT\tPRICE (%[1]s)\tVALUE (%[1]s)\t\n", r.Fiat)
+	for _, h := range r.Holdings {
+		fmt.Fprintf(w, "%s\t%.8f\t%s\t%s\t\n", h.Asset, h.Amount, optional(h.Price, "%.6f"), optional(h.Value, "%.2f"))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "CRO PURCHASES\t%s\t\n", r.Fiat)
+	fmt.Fprintf(w, "cost basis\t%.2f\t\n", r.Returns.CostBasis)
+	fmt.Fprintf(w, "CRO bought\t%.8f\t\n", r.Returns.CRO)
+	fmt.Fprintf(w, "average price\t%.6f\t\n", r.Returns.AveragePrice)
+	fmt.Fprintf(w, "price\t%.6f\t\n", r.Returns.Price)
+	fmt.Fprintf(w, "value\t%.2f\t\n", r.Returns.Value)
+	fmt.Fprintf(w, "gain\t%+.2f\t\n", r.Returns.Gain)
+	fmt.Fprintf(w, "total return\t%+.2f%%\t\n", r.Returns.TotalReturn*100)
+	fmt.Fprintf(w, "money weighted return (XIRR)\t%s\t\n", optionalPercent(r.Returns.XIRR))
+	fmt.Fprintf(w, "time weighted return\t%s\t\n", optionalPercent(r.Returns.TWR))
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "INCOME\tPAYOUTS\tCRO\tPAID VALUE (%[1]s)\tVALUE (%[1]s)\t\n", r.Fiat)
+	var total reportIncome
+	for _, i := range r.Income {
+		fmt.Fprintf(w, "%s\t%d\t%.8f\t%.2f\t%.2f\t\n", i.Source, i.Payouts, i.CRO, i.PaidValue, i.Value)
+		total.Payouts += i.Payouts
+		total.CRO += i.CRO
+		total.PaidValue += i.PaidValue
+		total.Value += i.Value
+	}
+	fmt.Fprintf(w, "total\t%d\t%.8f\t%.2f\t%.2f\t\n", total.Payouts, total.CRO, total.PaidValue, total.Value)
+
+	if len(r.Accounts) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "ACCOUNT\tBALANCE\tTOTAL BALANCE\tREWARDS CLAIMED\tREWARDS UNCLAIMED\tVALUE (%s)\t\n", r.Fiat)
+		for _, a := range r.Accounts {
+			fmt.Fprintf(w, "%s\t%.8f\t%.8f\t%.8f\t%.8f\t%.2f\t\n", a.Account, a.Balance, a.TotalBalance, a.RewardsClaimed, a.RewardsUnclaimed, a.TotalBalanceValue)
+		}
+	}
+	return w.Flush()
+}
+
+// writeCSV writes the report as section, name, field, value rows, which
+// keeps every table in one csv.
+func (r *report) writeCSV(out io.Writer) error {
+	w := csv.NewWriter(out)
+	row := func(section, name, field string, value interface{}) {
+		var formatted string
+		switch v := value.(type) {
+		case float64:
+			formatted = strconv.FormatFloat(v, 'f', -1, 64)
+		case *float64:
+			if v != nil {
+				formatted = strconv.FormatFloat(*v, 'f', -1, 64)
+			}
+		default:
+			formatted = fmt.Sprint(v)
+		}
+		w.Write([]string{section, name, field, formatted})
+	}
+	row("section", "name", "field", "value")
+	row("report", "", "time", r.Time.Format(time.RFC3339))
+	row("report", "", "fiat", r.Fiat)
+	for _, h := range r.Holdings {
+		row("holdings", h.Asset, "amount", h.Amount)
+		row("holdings", h.Asset, "price", h.Price)
+		row("holdings", h.Asset, "value", h.Value)
+	}
+	row("returns", "CRO", "cost_basis", r.Returns.CostBasis)
+	row("returns", "CRO", "cro", r.Returns.CRO)
+	row("returns", "CRO", "average_price", r.Returns.AveragePrice)
+	row("returns", "CRO", "price", r.Returns.Price)
+	row("returns", "CRO", "value", r.Returns.Value)
+	row("returns", "CRO", "gain", r.Returns.Gain)
+	row("returns", "CRO", "total_return", r.Returns.TotalReturn)
+	row("returns", "CRO", "xirr", r.Returns.XIRR)
+	row("returns", "CRO", "twr", r.Returns.TWR)
+	for _, i := range r.Income {
+		row("income", i.Source, "payouts", i.Payouts)
+		row("income", i.Source, "cro", i.CRO)
+		row("income", i.Source, "paid_value", i.PaidValue)
+		row("income", i.Source, "value", i.Value)
+	}
+	for _, a := range r.Accounts {
+		row("accounts", a.Account, "balance", a.Balance)
+		row("accounts", a.Account, "total_balance", a.TotalBalance)
+		row("accounts", a.Account, "rewards_claimed", a.RewardsClaimed)
+		row("accounts", a.Account, "rewards_unclaimed", a.RewardsUnclaimed)
+		row("accounts", a.Account, "total_balance_value", a.TotalBalanceValue)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func optional(v *float64, format string) string {
+	if v == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf(format, *v)
+}
+
+func optionalPercent(v *float64) string {
+	if v == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.2f%%", *v*100)
+}
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/lib"
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+const reportRows = "2021-04-01 08:00:00,Recurring Buy,USD,-10,CRO,50,USD,10,10,recurring_buy_order\n" +
+	"2021-01-01 08:00:00,Recurring Buy,USD,-10,CRO,100,USD,10,10,recurring_buy_order\n" +
+	"2021-04-02 09:00:00,CRO -> BTC,CRO,-50,BTC,0.0002,USD,10,10,crypto_exchange\n" +
+	"2021-04-02 09:00:00,CRO -> DOGE,CRO,-10,DOGE,5,USD,2,2,crypto_exchange\n" +
+	"2021-04-08 00:00:07,Crypto Earn,CRO,2,,,USD,0.3,0.3,crypto_earn_interest_paid\n" +
+	"2021-04-15 00:00:07,Crypto Earn,CRO,3,,,USD,0.5,0.5,crypto_earn_interest_paid\n" +
+	"2021-04-18 00:00:04,CRO Stake Rewards,CRO,1,,,USD,0.2,0.2,mco_stake_reward\n"
+
+// assetPrices prices the assets it lists and fails for every other one.
+type assetPrices map[string]float64
+
+func (p assetPrices) Price(ctx context.Context, asset, fiat string) (float64, error) {
+	price, ok := p[asset]
+	if !ok {
+		return 0, fmt.Errorf("unsupported asset %q", asset)
+	}
+	return price, nil
+}
+
+func runReport(t *testing.T, rows string, args ...string) (string, error) {
+	t.Helper()
+	original := newPriceClient
+	newPriceClient = func(server string) tracker.PriceProvider {
+		return assetPrices{"CRO": 0.2, "BTC": 50000}
+	}
+	t.Cleanup(func() {
+		newPriceClient = original
+		viper.Reset()
+	})
+
+	file := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := ioutil.WriteFile(file, []byte(exportHeader+rows), 0600); err != nil {
+		t.Fatal(err)
+	}
+	command := NewReportCommand()
+	var out bytes.Buffer
+	command.SetOut(&out)
+	command.SetErr(ioutil.Discard)
+	command.SetArgs(append([]string{"--file", file}, args...))
+	err := command.Execute()
+	return out.String(), err
+}
+
+func TestReportCommand(t *testing.T) {
+	out, err := runReport(t, reportRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"BTC 0.00020000 50000.000000 10.00",
+		"CRO 96.00000000 0.200000 19.20",
+		"DOGE 5.00000000 n/a n/a",
+		"cost basis 20.00",
+		"CRO bought 150.00000000",
+		"value 30.00",
+		"gain +10.00",
+		"total return +50.00%",
+		"money weighted return (XIRR) +",
+		// the price doubled after the first purchase and is back at the
+		// price of the second
+		"time weighted return +100.00%",
+		"crypto earn 2 5.00000000 0.80 1.00",
+		"staking rewards 1 1.00000000 0.20 0.20",
+		"total 3 6.00000000 1.00 1.20",
+	} {
+		if !strings.Contains(squeeze(out), want) {
+			t.Errorf("report is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+// squeeze collapses the padding of table cells into single spaces.
+func squeeze(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestReportWithoutPurchases(t *testing.T) {
+	out, err := runReport(t, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(squeeze(out), "time weighted return n/a") {
+		t.Errorf("expected the returns to be n/a:\n%s", out)
+	}
+}
+
+func TestReportOutputFormats(t *testing.T) {
+	out, err := runReport(t, reportRows, "--output", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON report
+	if err := json.Unmarshal([]byte(out), &fromJSON); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, out)
+	}
+	if len(fromJSON.Holdings) != 3 || fromJSON.Holdings[2].Price != nil {
+		t.Errorf("json holdings = %+v, want DOGE without a price", fromJSON.Holdings)
+	}
+	if fromJSON.Returns.TWR == nil || *fromJSON.Returns.TWR != 1 {
+		t.Errorf("json returns = %+v, want a twr of 1", fromJSON.Returns)
+	}
+
+	out, err = runReport(t, reportRows, "-o", "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromYAML report
+	if err := yaml.Unmarshal([]byte(out), &fromYAML); err != nil {
+		t.Fatalf("invalid yaml: %v\n%s", err, out)
+	}
+	if len(fromYAML.Income) != 2 || fromYAML.Income[0].Source != tracker.EarnIncome || fromYAML.Income[0].CRO != 5 {
+		t.Errorf("yaml income = %+v, want 5 CRO of crypto earn first", fromYAML.Income)
+	}
+
+	out, err = runReport(t, reportRows, "-o", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v\n%s", err, out)
+	}
+	found := map[string]string{}
+	for _, record := range records[1:] {
+		found[strings.Join(record[:3], ",")] = record[3]
+	}
+	for key, want := range map[string]string{
+		"holdings,CRO,amount":    "96",
+		"holdings,DOGE,price":    "",
+		"returns,CRO,cost_basis": "20",
+		"returns,CRO,twr":        "1",
+		"income,crypto earn,cro": "5",
+	} {
+		if got, ok := found[key]; !ok || got != want {
+			t.Errorf("csv %s = %q, want %q:\n%s", key, got, want, out)
+		}
+	}
+
+	_, err = runReport(t, reportRows, "-o", "xml")
+	if code := ExitCode(err); code != ExitConfig {
+		t.Errorf("unknown output: exit code %d, want %d (%v)", code, ExitConfig, err)
+	}
+}
+
+func TestReportWithAccount(t *testing.T) {
+	useExplorer(t, &lib.ExplorerClientInterfaceMock{
+		GetAccountFunc: func(ctx context.Context, opts *lib.GetAccountOpts) (*lib.GetAccountResponse, error) {
+			return &lib.GetAccountResponse{Result: lib.Result{
+				Address:      opts.AccountID,
+				Balance:      []lib.Coin{{Denom: lib.BaseCRODenom, Amount: "10000000000"}},
+				Totalbalance: []lib.Coin{{Denom: lib.BaseCRODenom, Amount: "50000000000"}},
+				Totalrewards: []lib.Coin{{Denom: lib.BaseCRODenom, Amount: "150000000"}},
+			}}, nil
+		},
+		ListAccountTransactionsFunc: func(ctx context.Context, account string) ([]lib.TransactionResult, error) {
+			return []lib.TransactionResult{{
+				Hash:      "CLAIM",
+				Blocktime: time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC),
+				Success:   true,
+				Feepayer:  account,
+				Messages: []lib.Messages{{
+					Type: "/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward",
+					Content: lib.Content{
+						Delegatoraddress: account,
+						Amount:           lib.Coins{{Denom: lib.BaseCRODenom, Amount: "250000000"}},
+					},
+				}},
+			}}, nil
+		},
+	})
+	out, err := runReport(t, reportRows, "--account-id", reconcileAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := reconcileAccount + " 100.00000000 500.00000000 2.50000000 1.50000000 100.00"
+	if !strings.Contains(squeeze(out), want) {
+		t.Errorf("report is missing %q:\n%s", want, out)
+	}
+}
+
+func TestReportMergesExports(t *testing.T) {
+	// a later export repeating the first purchase and adding another
+	dir := t.TempDir()
+	later := exportHeader +
+		"2021-05-01 08:00:00,Recurring Buy,USD,-10,CRO,40,USD,10,10,recurring_buy_order\n" +
+		"2021-01-01 08:00:00,Recurring Buy,USD,-10,CRO,100,USD,10,10,recurring_buy_order\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "later.csv"), []byte(later), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out, err := runReport(t, reportRows, "--file", filepath.Join(dir, "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"cost basis 30.00", "CRO bought 190.00000000", "CRO 136.00000000"} {
+		if !strings.Contains(squeeze(out), want) {
+			t.Errorf("report is missing %q:\n%s", want, out)
+		}
+	}
+
+	_, err = runReport(t, reportRows, "--file", filepath.Join(dir, "*.json"))
+	if ExitCode(err) != ExitData || !strings.Contains(err.Error(), "no exports match") {
+		t.Errorf("err = %v (exit %d), want no matching exports (exit %d)", err, ExitCode(err), ExitData)
+	}
+}
+
+func TestReportAccountErrors(t *testing.T) {
+	useExplorer(t, &lib.ExplorerClientInterfaceMock{
+		GetAccountFunc: func(ctx context.Context, opts *lib.GetAccountOpts) (*lib.GetAccountResponse, error) {
+			return nil, errors.New("explorer unreachable")
+		},
+	})
+	_, err := runReport(t, reportRows, "--account-id", reconcileAccount)
+	if ExitCode(err) != ExitNetwork {
+		t.Errorf("unreachable explorer: err = %v (exit %d), want exit %d", err, ExitCode(err), ExitNetwork)
+	}
+
+	useExplorer(t, &lib.ExplorerClientInterfaceMock{
+		GetAccountFunc: func(ctx context.Context, opts *lib.GetAccountOpts) (*lib.GetAccountResponse, error) {
+			return &lib.GetAccountResponse{Result: lib.Result{
+				Address: opts.AccountID,
+				Balance: []lib.Coin{{Denom: lib.BaseCRODenom, Amount: "lots"}},
+			}}, nil
+		},
+		ListAccountTransactionsFunc: func(ctx context.Context, account string) ([]lib.TransactionResult, error) {
+			return nil, nil
+		},
+	})
+	_, err = runReport(t, reportRows, "--account-id", reconcileAccount)
+	if ExitCode(err) != ExitData {
+		t.Errorf("malformed balance: err = %v (exit %d), want exit %d", err, ExitCode(err), ExitData)
+	}
+}
+
+func TestReportPriceUnavailable(t *testing.T) {
+	_, err := newReport(context.Background(), nil, assetPrices{}, "USD", time.Now())
+	if ExitCode(err) != ExitNetwork {
+		t.Errorf("err = %v (exit %d), want exit %d", err, ExitCode(err), ExitNetwork)
+	}
+}
+
+func TestReportHoldingWithoutPrice(t *testing.T) {
+	transactions, err := tracker.ParseTransactions(strings.NewReader(exportHeader + reportRows))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := newReport(context.Background(), transactions, assetPrices{"CRO": 0.2, "BTC": 50000}, "USD", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.priceErrors) != 1 || !strings.Contains(r.priceErrors[0].Error(), `no price for DOGE, listed without a value; unsupported asset "DOGE"`) {
+		t.Errorf("price errors = %v, want DOGE's", r.priceErrors)
+	}
+}
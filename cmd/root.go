@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -16,30 +18,58 @@ func NewRootCommand() *cobra.Command {
 	command := &cobra.Command{
 		Use:   "crypto-tracker",
 		Short: "Import Crypto.com transactions into google sheets",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// arguments have been parsed, so any error from here on isn't
+			// a usage mistake
+			cmd.SilenceUsage = true
+			return configError(initConfig())
+		},
 	}
-	cobra.OnInitialize(initConfig)
+	setDefaults()
 
 	command.AddCommand(NewLoginCommand())
+	command.AddCommand(NewLogoutCommand())
 	command.AddCommand(NewImportCommand())
+	command.AddCommand(NewDaemonCommand())
+	command.AddCommand(NewRefreshPricesCommand())
+	command.AddCommand(NewReportCommand())
+	command.AddCommand(NewReconcileCommand())
+	command.AddCommand(NewExportCommand())
+	command.AddCommand(NewConfigCommand())
 
 	command.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.crypto-tracker.yaml)")
+	command.PersistentFlags().String("profile", "", "named profile from the config file to use")
+	cobra.CheckErr(viper.BindPFlag("profile", command.PersistentFlags().Lookup("profile")))
 	command.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	return command
 }
 
-func initConfig() {
+func initConfig() error {
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	} else {
 		home, err := homedir.Dir()
-		cobra.CheckErr(err)
+		if err != nil {
+			return err
+		}
 
 		viper.AddConfigPath(home)
 		viper.SetConfigName(".crypto-tracker")
 	}
 
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv()
-	if err := viper.ReadInConfig(); err == nil {
+	err := viper.ReadInConfig()
+	var notFound viper.ConfigFileNotFoundError
+	switch {
+	case err == nil:
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	case !errors.As(err, &notFound):
+		return fmt.Errorf("failed to read config file; %w", err)
 	}
+	if err := applyProfile(); err != nil {
+		return err
+	}
+	return registerFormats()
 }
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/oauth2"
+	"golang.org/x/term"
+)
+
+// TokenStore persists the google oauth token between runs.
+type TokenStore interface {
+	// Load returns an error matching os.ErrNotExist when no token has been
+	// saved yet.
+	Load() (*oauth2.Token, error)
+
+	Save(token *oauth2.Token) error
+
+	Delete() error
+
+	// Location describes where the token is kept
+	Location() string
+}
+
+// supported token stores
+const (
+	// plaintext json, readable only by the current user
+	tokenStoreFile = "file"
+	// AES-GCM encrypted json
+	tokenStoreEncrypted = "encrypted"
+)
+
+func newTokenStore(kind, path string) (TokenStore, error) {
+	switch kind {
+	case tokenStoreFile, "":
+		return &FileTokenStore{Path: path}, nil
+	case tokenStoreEncrypted:
+		return &EncryptedTokenStore{Path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown token store %q", kind)
+	}
+}
+
+// FileTokenStore keeps the token as plaintext json.
+type FileTokenStore struct {
+	Path string
+}
+
+func (s *FileTokenStore) Load() (*oauth2.Token, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tok := &oauth2.Token{}
+	err = json.NewDecoder(f).Decode(tok)
+	return tok, err
+}
+
+func (s *FileTokenStore) Save(token *oauth2.Token) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(s.Path, b)
+}
+
+func (s *FileTokenStore) Delete() error {
+	return os.Remove(s.Path)
+}
+
+func (s *FileTokenStore) Location() string {
+	return s.Path
+}
+
+// EncryptedTokenStore keeps the token encrypted with AES-256-GCM.
+//
+// The key is taken, in order of preference, from CRYPTO_TRACKER_TOKEN_KEY (32
+// base64 encoded bytes), or derived with scrypt from the passphrase in
+// CRYPTO_TRACKER_TOKEN_PASSPHRASE or typed at the terminal. The salt and KDF
+// are stored alongside the ciphertext, so the file can be opened on any OS.
+type EncryptedTokenStore struct {
+	Path string
+}
+
+// encryptedToken is the on-disk format of an EncryptedTokenStore.
+type encryptedToken struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+const (
+	encryptedTokenVersion = 1
+
+	// the key was supplied directly
+	kdfNone = "none"
+	// the key was derived from a passphrase
+	kdfScrypt = "scrypt"
+)
+
+// additional data authenticated with every encrypted token
+var encryptedTokenAD = []byte("crypto-tracker token")
+
+func (s *EncryptedTokenStore) Load() (*oauth2.Token, error) {
+	b, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	var sealed encryptedToken
+	if err := json.Unmarshal(b, &sealed); err != nil || sealed.Version == 0 {
+		return nil, fmt.Errorf("%s is not an encrypted token, log out and in again", s.Path)
+	}
+	if sealed.Version != encryptedTokenVersion {
+		return nil, fmt.Errorf("unsupported encrypted token version %d", sealed.Version)
+	}
+	key, err := tokenKey(sealed.KDF, sealed.Salt, false)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, encryptedTokenAD)
+	if err != nil {
+		return nil, errors.New("unable to decrypt token, wrong passphrase or key")
+	}
+	tok := &oauth2.Token{}
+	err = json.Unmarshal(plaintext, tok)
+	return tok, err
+}
+
+func (s *EncryptedTokenStore) Save(token *oauth2.Token) error {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	sealed := encryptedToken{
+		Version: encryptedTokenVersion,
+		KDF:     kdfScrypt,
+	}
+	if os.Getenv(envName("token-key")) != "" {
+		sealed.KDF = kdfNone
+	} else if sealed.Salt, err = randomBytes(16); err != nil {
+		return err
+	}
+	key, err := tokenKey(sealed.KDF, sealed.Salt, true)
+	if err != nil {
+		return err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	if sealed.Nonce, err = randomBytes(aead.NonceSize()); err != nil {
+		return err
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, encryptedTokenAD)
+	b, err := json.Marshal(sealed)
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(s.Path, b)
+}
+
+func (s *EncryptedTokenStore) Delete() error {
+	return os.Remove(s.Path)
+}
+
+func (s *EncryptedTokenStore) Location() string {
+	return s.Path + " (encrypted)"
+}
+
+// tokenKey resolves the 32 byte encryption key for a token sealed with kdf.
+// When confirm is set a typed passphrase has to be entered twice.
+func tokenKey(kdf string, salt []byte, confirm bool) ([]byte, error) {
+	switch kdf {
+	case kdfNone:
+		encoded := os.Getenv(envName("token-key"))
+		if encoded == "" {
+			return nil, fmt.Errorf("token was encrypted with a key, set %s", envName("token-key"))
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("%s must be 32 base64 encoded bytes", envName("token-key"))
+		}
+		return key, nil
+	case kdfScrypt:
+		passphrase, err := tokenPassphrase(confirm)
+		if err != nil {
+			return nil, err
+		}
+		return scrypt.Key(passphrase, salt, 1<<15, 8, 1, 32)
+	default:
+		return nil, fmt.Errorf("unknown key derivation %q", kdf)
+	}
+}
+
+func tokenPassphrase(confirm bool) ([]byte, error) {
+	if passphrase := os.Getenv(envName("token-passphrase")); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("no terminal to read the token passphrase from, set %s", envName("token-passphrase"))
+	}
+	fmt.Fprint(os.Stderr, "Token passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("empty token passphrase")
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Confirm token passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		if string(again) != string(passphrase) {
+			return nil, errors.New("token passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+// writePrivateFile replaces path with b, readable only by the current user.
+// It writes a temporary file and renames it, so a file that existed before
+// doesn't keep wider permissions, and a failed write leaves it intact.
+func writePrivateFile(path string, b []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// TempFile creates the file with 0600 permissions
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
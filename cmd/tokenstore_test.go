@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+var testToken = &oauth2.Token{AccessToken: "secret-access", RefreshToken: "secret-refresh", TokenType: "Bearer"}
+
+func TestEncryptedTokenStore(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	for _, test := range []struct {
+		name  string
+		env   string
+		value string
+		wrong string
+	}{
+		{name: "passphrase", env: "CRYPTO_TRACKER_TOKEN_PASSPHRASE", value: "correct horse", wrong: "battery staple"},
+		{name: "key", env: "CRYPTO_TRACKER_TOKEN_KEY", value: key, wrong: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{8}, 32))},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			setEnv(t, test.env, test.value)
+			store := &EncryptedTokenStore{Path: filepath.Join(t.TempDir(), "token.json")}
+			if err := store.Save(testToken); err != nil {
+				t.Fatal(err)
+			}
+
+			tok, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tok.AccessToken != testToken.AccessToken || tok.RefreshToken != testToken.RefreshToken {
+				t.Errorf("loaded %+v, want %+v", tok, testToken)
+			}
+
+			b, err := ioutil.ReadFile(store.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(b), "secret") {
+				t.Errorf("stored token isn't encrypted: %s", b)
+			}
+
+			setEnv(t, test.env, test.wrong)
+			if _, err := store.Load(); err == nil || !strings.Contains(err.Error(), "wrong passphrase or key") {
+				t.Errorf("err = %v, want the wrong %s reported", err, test.name)
+			}
+		})
+	}
+}
+
+func TestEncryptedTokenStorePlaintext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	if err := (&FileTokenStore{Path: path}).Save(testToken); err != nil {
+		t.Fatal(err)
+	}
+	_, err := (&EncryptedTokenStore{Path: path}).Load()
+	if err == nil || !strings.Contains(err.Error(), "not an encrypted token") {
+		t.Errorf("err = %v, want a plaintext token refused", err)
+	}
+}
+
+func TestWritePrivateFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows doesn't have unix permissions")
+	}
+	path := filepath.Join(t.TempDir(), "token.json")
+	if err := ioutil.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writePrivateFile(path, []byte(`{"access_token":"secret"}`)); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("permissions %v, want -rw-------", perm)
+	}
+	if entries, _ := ioutil.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("%d files left behind, want only the token", len(entries))
+	}
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+)
+
+const defaultWatchInterval = 30 * time.Second
+
+// exportWatcher polls an export, or a directory of exports, for changes.
+type exportWatcher struct {
+	// path is the export file, or a directory whose newest csv file is used
+	path string
+
+	file    string
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+// newestExport resolves the export to import: path itself, or the most
+// recently modified csv file when path is a directory.
+func newestExport(path string) (os.FileInfo, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.IsDir() {
+		return info, path, nil
+	}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, "", err
+	}
+	var newest os.FileInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".csv") {
+			continue
+		}
+		if newest == nil || entry.ModTime().After(newest.ModTime()) {
+			newest = entry
+		}
+	}
+	if newest == nil {
+		return nil, "", fmt.Errorf("no csv exports in %s", path)
+	}
+	return newest, filepath.Join(path, newest.Name()), nil
+}
+
+// changed returns the content of the export when it differs from the last
+// call. The file is only read when its name, size or mtime changed, and a
+// changed mtime with identical content doesn't count.
+func (w *exportWatcher) changed() (string, []byte, bool, error) {
+	info, file, err := newestExport(w.path)
+	if err != nil {
+		return "", nil, false, err
+	}
+	if file == w.file && info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return file, nil, false, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", nil, false, err
+	}
+	sum := sha256.Sum256(data)
+	changed := file != w.file || sum != w.sum
+	w.file, w.modTime, w.size, w.sum = file, info.ModTime(), info.Size(), sum
+	return file, data, changed, nil
+}
+
+// reset forgets the last seen export, so the next call reports it as changed.
+func (w *exportWatcher) reset() {
+	*w = exportWatcher{path: w.path}
+}
+
+// watchImport syncs the export into the sheet every time it changes, until
+// ctx is done. Exports that can't be read or parsed, e.g. while they are
+// still being written, are reported and retried on the next change; failed
+// API calls are retried on the next tick unless google rejected the
+// credentials.
+func watchImport(ctx context.Context, out io.Writer, importer *tracker.TransactionImporter, prices tracker.PriceProvider, path string, interval time.Duration) error {
+	if interval <= 0 {
+		return configError(fmt.Errorf("watch-interval must be positive, got %s", interval))
+	}
+	watcher := &exportWatcher{path: path}
+	logf := timestampLogger(out)
+	logf("watching %s every %s", path, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		file, data, changed, err := watcher.changed()
+		switch {
+		case err != nil:
+			logf("skipping %s: %v", path, err)
+		case changed:
+			added, err := importer.Sync(ctx, bytes.NewReader(data), prices)
+			var parseErr *tracker.ParseError
+			switch {
+			case errors.As(err, &parseErr):
+				logf("skipping %s: %v", file, err)
+			case err != nil:
+				err = importError(fmt.Errorf("failed to import %s; %w", file, err))
+				if ExitCode(err) == ExitAuth {
+					return err
+				}
+				// try again on the next tick
+				watcher.reset()
+				logf("%v", err)
+			case len(added) == 0:
+				logf("%s changed, nothing new", file)
+			default:
+				logf("imported %d new transactions from %s", len(added), file)
+				for _, t := range added {
+					logf("  %s %s %s", t.Timestamp.Format(tracker.TimestampLayout), t.Description, describeAmounts(t))
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			logf("stopped watching %s", path)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// timestampLogger prints a line to out, prefixed with the local time, for
+// commands that keep running.
+func timestampLogger(out io.Writer) func(format string, a ...interface{}) {
+	return func(format string, a ...interface{}) {
+		fmt.Fprintf(out, "%s %s\n", time.Now().Format(tracker.TimestampLayout), fmt.Sprintf(format, a...))
+	}
+}
+
+func describeAmounts(t tracker.Transaction) string {
+	s := fmt.Sprintf("%g %s", t.Amount, t.Currency)
+	if t.ToCurrency != "" {
+		s += fmt.Sprintf(" -> %g %s", t.ToAmount, t.ToCurrency)
+	}
+	return s
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/igaskin/crypto-tracker/tracker/sheetstest"
+)
+
+const exportHeader = "Timestamp (UTC),Transaction Description,Currency,Amount,To Currency,To Amount,Native Currency,Native Amount,Native Amount (in USD),Transaction Kind\n"
+
+func writeExport(t *testing.T, path string, rows string, modTime time.Time) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(exportHeader+rows), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportWatcher(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	week1 := filepath.Join(dir, "week1.csv")
+	writeExport(t, week1, "2021-03-01 08:00:00,Recurring Buy,USD,-25,CRO,125,USD,25,25,recurring_buy_order\n", start)
+	if err := ioutil.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := &exportWatcher{path: dir}
+	check := func(wantFile string, wantChanged bool) {
+		t.Helper()
+		file, _, changed, err := watcher.changed()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file != wantFile || changed != wantChanged {
+			t.Errorf("changed() = %s, %v, want %s, %v", file, changed, wantFile, wantChanged)
+		}
+	}
+	check(week1, true)
+	check(week1, false)
+
+	// touched without changing the content
+	if err := os.Chtimes(week1, start.Add(time.Minute), start.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	check(week1, false)
+
+	week2 := filepath.Join(dir, "week2.csv")
+	writeExport(t, week2, "2021-03-08 08:00:00,Recurring Buy,USD,-25,CRO,100,USD,25,25,recurring_buy_order\n", start.Add(2*time.Minute))
+	check(week2, true)
+
+	writeExport(t, week2, "2021-03-09 08:00:00,Recurring Buy,USD,-25,CRO,100,USD,25,25,recurring_buy_order\n", start.Add(3*time.Minute))
+	check(week2, true)
+}
+
+// syncBuffer is a bytes.Buffer safe to read while the watcher writes to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type fixedPrice float64
+
+func (p fixedPrice) Price(ctx context.Context, asset, fiat string) (float64, error) {
+	return float64(p), nil
+}
+
+// newSheetImporter starts a fake sheets server holding a spreadsheet with an
+// empty ROI tab, and an importer writing to it.
+func newSheetImporter(t *testing.T) (*sheetstest.Server, *tracker.TransactionImporter) {
+	t.Helper()
+	server := sheetstest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddSpreadsheet("sheet", "ROI")
+	service, err := server.Service(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer, err := tracker.NewTransactionImporter(tracker.TransactionImporterOpts{
+		Googlesheet:   service,
+		SpreadsheetID: "sheet",
+		SheetName:     "ROI",
+		StartRow:      1,
+		StartColumn:   "A",
+		Fiat:          "USD",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, importer
+}
+
+func TestWatchImport(t *testing.T) {
+	server, importer := newSheetImporter(t)
+
+	export := filepath.Join(t.TempDir(), "export.csv")
+	start := time.Now().Add(-time.Hour)
+	writeExport(t, export, "2021-03-01 08:00:00,Recurring Buy,USD,-25,CRO,125,USD,25,25,recurring_buy_order\n", start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() {
+		done <- watchImport(ctx, &out, importer, fixedPrice(0.5), export, 5*time.Millisecond)
+	}()
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s, output:\n%s", what, out.String())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	logged := func(s string) func() bool {
+		return func() bool { return strings.Contains(out.String(), s) }
+	}
+	waitFor("the first import", logged("imported 1 new transactions"))
+	// header, one purchase and the summary
+	if rows := len(server.Grid("sheet", "ROI")); rows != 3 {
+		t.Errorf("got %d rows after the first import, want 3", rows)
+	}
+
+	// a malformed export is skipped and the sheet left alone
+	writeExport(t, export, "not a timestamp,Recurring Buy\n", start.Add(time.Minute))
+	waitFor("the malformed export to be skipped", logged("skipping"))
+
+	writeExport(t, export,
+		"2021-03-08 08:00:00,Recurring Buy,USD,-25,CRO,100,USD,25,25,recurring_buy_order\n"+
+			"2021-03-01 08:00:00,Recurring Buy,USD,-25,CRO,125,USD,25,25,recurring_buy_order\n",
+		start.Add(2*time.Minute))
+	waitFor("the second import", logged("-> 100 CRO"))
+	if rows := len(server.Grid("sheet", "ROI")); rows != 4 {
+		t.Errorf("got %d rows after the second import, want 4", rows)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	log := out.String()
+	for _, want := range []string{
+		"imported 1 new transactions from " + export,
+		"2021-03-01 08:00:00 Recurring Buy -25 USD -> 125 CRO",
+		"2021-03-08 08:00:00 Recurring Buy -25 USD -> 100 CRO",
+		"stopped watching",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log is missing %q:\n%s", want, log)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package lib
+
+import (
+	"strings"
+	"time"
+)
+
+// Transfer is CRO sent to or from an account on chain.
+type Transfer struct {
+	Time   time.Time
+	Hash   string
+	Amount float64 // positive when received, negative when sent
+}
+
+// Claim is a withdrawal of staking rewards by an account on chain.
+type Claim struct {
+	Time   time.Time
+	Hash   string
+	Amount float64
+	// Fee is the fee of the claiming transaction, which the account
+	// initiated
+	Fee float64
+}
+
+// Activity totals the CRO an account moved on chain, in whole CRO.
+type Activity struct {
+	Received       float64
+	Sent           float64
+	RewardsClaimed float64
+	// fees of the transactions the account initiated
+	Fees      float64
+	Transfers []Transfer
+	Claims    []Claim
+}
+
+// AccountActivity walks the successful transactions of an account's history,
+// totalling what it received, sent and claimed, and the fees it paid.
+func AccountActivity(history []TransactionResult, address string) (*Activity, error) {
+	a := &Activity{}
+	for _, tx := range history {
+		if !tx.Success {
+			continue
+		}
+		initiated := tx.Feepayer == address
+		var claimed float64
+		for _, msg := range tx.Messages {
+			amount, err := SumCRO(msg.Content.Amount)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case strings.HasSuffix(msg.Type, "MsgSend"):
+				if msg.Content.Toaddress == address {
+					a.Received += amount
+					a.Transfers = append(a.Transfers, Transfer{Time: tx.Blocktime, Hash: tx.Hash, Amount: amount})
+				}
+				if msg.Content.Fromaddress == address {
+					a.Sent += amount
+					initiated = true
+					a.Transfers = append(a.Transfers, Transfer{Time: tx.Blocktime, Hash: tx.Hash, Amount: -amount})
+				}
+			case strings.HasSuffix(msg.Type, "MsgWithdrawDelegatorReward"):
+				if msg.Content.Delegatoraddress == address {
+					claimed += amount
+					initiated = true
+				}
+			default:
+				if msg.Content.Delegatoraddress == address {
+					initiated = true
+				}
+			}
+		}
+		var fee float64
+		if initiated {
+			var err error
+			if fee, err = SumCRO(tx.Fee); err != nil {
+				return nil, err
+			}
+			a.Fees += fee
+		}
+		if claimed != 0 {
+			a.RewardsClaimed += claimed
+			a.Claims = append(a.Claims, Claim{Time: tx.Blocktime, Hash: tx.Hash, Amount: claimed, Fee: fee})
+		}
+	}
+	return a, nil
+}
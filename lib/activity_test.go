@@ -0,0 +1,63 @@
+package lib_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/lib"
+)
+
+func basecro(amount string) lib.Coins {
+	return lib.Coins{{Denom: lib.BaseCRODenom, Amount: amount}}
+}
+
+func TestAccountActivity(t *testing.T) {
+	const other = "cro1other"
+	at := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	send := func(from, to, amount string) lib.Messages {
+		return lib.Messages{Type: "/cosmos.bank.v1beta1.MsgSend", Content: lib.Content{Fromaddress: from, Toaddress: to, Amount: basecro(amount)}}
+	}
+	history := []lib.TransactionResult{
+		// received from the app, which paid the fee
+		{Hash: "IN", Blocktime: at, Success: true, Feepayer: other, Fee: basecro("5000"),
+			Messages: []lib.Messages{send(other, account, "20000000000")}},
+		{Hash: "OUT", Blocktime: at.Add(time.Hour), Success: true, Feepayer: account, Fee: basecro("5000"),
+			Messages: []lib.Messages{send(account, other, "5000000000")}},
+		{Hash: "CLAIM", Blocktime: at.Add(2 * time.Hour), Success: true, Feepayer: account, Fee: basecro("5000"),
+			Messages: []lib.Messages{{Type: "/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward",
+				Content: lib.Content{Delegatoraddress: account, Amount: basecro("100000000")}}}},
+		{Hash: "DELEGATE", Blocktime: at.Add(3 * time.Hour), Success: true, Fee: basecro("5000"),
+			Messages: []lib.Messages{{Type: "/cosmos.staking.v1beta1.MsgDelegate",
+				Content: lib.Content{Delegatoraddress: account, Amount: basecro("1000000000")}}}},
+		// failed transactions don't move anything
+		{Hash: "FAILED", Blocktime: at.Add(4 * time.Hour), Success: false, Feepayer: account, Fee: basecro("5000"),
+			Messages: []lib.Messages{send(account, other, "100000000000")}},
+	}
+	a, err := lib.AccountActivity(history, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, got := range map[string][2]float64{
+		"received": {a.Received, 200},
+		"sent":     {a.Sent, 50},
+		"claimed":  {a.RewardsClaimed, 1},
+		"fees":     {a.Fees, 0.00015},
+	} {
+		if math.Abs(got[0]-got[1]) > 1e-9 {
+			t.Errorf("%s = %.8f, want %.8f", name, got[0], got[1])
+		}
+	}
+	if len(a.Transfers) != 2 || a.Transfers[0].Hash != "IN" || a.Transfers[1].Amount != -50 {
+		t.Errorf("transfers = %+v, want IN and OUT", a.Transfers)
+	}
+	if want := []lib.Claim{{Time: at.Add(2 * time.Hour), Hash: "CLAIM", Amount: 1, Fee: 0.00005}}; !reflect.DeepEqual(a.Claims, want) {
+		t.Errorf("claims = %+v, want %+v", a.Claims, want)
+	}
+
+	history[0].Messages[0].Content.Amount = lib.Coins{{Denom: "uatom", Amount: "1"}}
+	if _, err := lib.AccountActivity(history, account); err == nil {
+		t.Error("expected an unknown denom to fail")
+	}
+}
@@ -1,21 +1,28 @@
 package lib
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
 
+//go:generate moq -out explorer_mock.go . ExplorerClientInterface
+
 type ExplorerClientInterface interface {
 	// GetAccount request
 	GetAccount(ctx context.Context, opts *GetAccountOpts) (*GetAccountResponse, error)
 
 	// GetAccountTransaction request
 	GetAccountTransaction(ctx context.Context, opts *GetAccountTransactionOpts) (*GetAccountTransactionResponse, error)
+
+	// ListAccountTransactions pages through GetAccountTransaction
+	ListAccountTransactions(ctx context.Context, account string) ([]TransactionResult, error)
 }
 
 type ExplorerClient struct {
@@ -43,39 +50,133 @@ func NewExplorerClient(server string) *ExplorerClient {
 }
 
 func (c *ExplorerClient) GetAccount(ctx context.Context, opts *GetAccountOpts) (*GetAccountResponse, error) {
-	var err error
-
-	serverURL, err := url.Parse(c.Server)
-	if err != nil {
+	operationPath := fmt.Sprintf("accounts/%s", url.PathEscape(opts.AccountID))
+	var account GetAccountResponse
+	if err := c.get(ctx, operationPath, nil, &account); err != nil {
 		return nil, err
 	}
+	return &account, nil
+}
 
-	operationPath := fmt.Sprintf("/accounts/%s", opts.AccountID)
-	if operationPath[0] == '/' {
-		operationPath = operationPath[1:]
+func (c *ExplorerClient) GetAccountTransaction(ctx context.Context, opts *GetAccountTransactionOpts) (*GetAccountTransactionResponse, error) {
+	operationPath := fmt.Sprintf("accounts/%s/transactions", url.PathEscape(opts.Account))
+	query := url.Values{}
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(int(opts.Page)))
 	}
-	operationURL := url.URL{
-		Path: operationPath,
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(int(opts.Limit)))
 	}
+	if opts.Order != "" {
+		query.Set("order", opts.Order)
+	}
+	var transactions GetAccountTransactionResponse
+	if err := c.get(ctx, operationPath, query, &transactions); err != nil {
+		return nil, err
+	}
+	return &transactions, nil
+}
 
-	queryURL := serverURL.ResolveReference(&operationURL)
+// ExplorerError is a non 200 response from the explorer.
+type ExplorerError struct {
+	StatusCode int
+	Status     string
+	// Message is the error reported in the response body, if any
+	Message string
+}
 
-	req, err := http.NewRequest("GET", queryURL.String(), nil)
+func (e *ExplorerError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("explorer returned %s", e.Status)
+	}
+	return fmt.Sprintf("explorer returned %s: %s", e.Status, e.Message)
+}
+
+func (c *ExplorerClient) get(ctx context.Context, operationPath string, query url.Values, v interface{}) error {
+	serverURL, err := url.Parse(c.Server)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	operationURL, err := url.Parse(operationPath)
+	if err != nil {
+		return err
+	}
+	operationURL.RawQuery = query.Encode()
+	queryURL := serverURL.ResolveReference(operationURL)
 
+	req, err := http.NewRequestWithContext(ctx, "GET", queryURL.String(), nil)
+	if err != nil {
+		return err
+	}
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
-	var account GetAccountResponse
-	if err := json.NewDecoder(resp.Body).Decode(&account); err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		explorerErr := &ExplorerError{StatusCode: resp.StatusCode, Status: resp.Status}
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil {
+			explorerErr.Message = body.Error
+		}
+		return explorerErr
 	}
-	return &account, nil
+	return json.NewDecoder(resp.Body).Decode(v)
+}
 
+// ListAccountTransactions walks every page of an account's transactions,
+// oldest first.
+func (c *ExplorerClient) ListAccountTransactions(ctx context.Context, account string) ([]TransactionResult, error) {
+	var all []TransactionResult
+	for page := int32(1); ; page++ {
+		resp, err := c.GetAccountTransaction(ctx, &GetAccountTransactionOpts{
+			Account: account,
+			Page:    page,
+			Limit:   100,
+			Order:   "height.asc",
+		})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, resp.Result...)
+		if len(resp.Result) == 0 || resp.Pagination.CurrentPage >= resp.Pagination.TotalPage {
+			return all, nil
+		}
+	}
+}
+
+// BaseCRODenom is the smallest on-chain unit of CRO; 1 CRO = 10^8 basecro.
+const BaseCRODenom = "basecro"
+
+// CRO converts the coin into whole CRO.
+func (c Coin) CRO() (float64, error) {
+	value, err := strconv.ParseFloat(c.Amount, 64)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(c.Denom) {
+	case BaseCRODenom:
+		return value / 1e8, nil
+	case "cro":
+		return value, nil
+	default:
+		return 0, fmt.Errorf("unknown denom %q", c.Denom)
+	}
+}
+
+// SumCRO totals a list of coins in whole CRO.
+func SumCRO(coins []Coin) (float64, error) {
+	var total float64
+	for _, c := range coins {
+		value, err := c.CRO()
+		if err != nil {
+			return 0, err
+		}
+		total += value
+	}
+	return total, nil
 }
 
 type GetAccountOpts struct {
@@ -85,22 +186,39 @@ type GetAccountOpts struct {
 type GetAccountResponse struct {
 	Result Result `json:"result"`
 }
-type Balance struct {
-	Denom  string `json:"denom"`
-	Amount string `json:"amount"`
-}
-type Bondedbalance struct {
-	Denom  string `json:"denom"`
-	Amount string `json:"amount"`
-}
-type Totalrewards struct {
+
+// Coin is an amount of a single denom as reported by the explorer.
+type Coin struct {
 	Denom  string `json:"denom"`
 	Amount string `json:"amount"`
 }
-type Totalbalance struct {
-	Denom  string `json:"denom"`
-	Amount string `json:"amount"`
+
+// Coins is a list of amounts. The explorer reports some message amounts, such
+// as a delegation's, as a single coin rather than a list; both decode.
+type Coins []Coin
+
+func (c *Coins) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var coin Coin
+		if err := json.Unmarshal(trimmed, &coin); err != nil {
+			return err
+		}
+		*c = Coins{coin}
+		return nil
+	}
+	var coins []Coin
+	if err := json.Unmarshal(trimmed, &coins); err != nil {
+		return err
+	}
+	*c = coins
+	return nil
 }
+
+type Balance = Coin
+type Bondedbalance = Coin
+type Totalrewards = Coin
+type Totalbalance = Coin
 type Result struct {
 	Type                string          `json:"type"`
 	Name                string          `json:"name"`
@@ -118,7 +236,7 @@ type GetAccountTransactionOpts struct {
 	// page=5&limit=8&order=height.desc
 	Account string
 	Page    int32
-	limit   int32
+	Limit   int32
 	Order   string
 }
 
@@ -126,26 +244,22 @@ type GetAccountTransactionResponse struct {
 	Result     []TransactionResult `json:"result"`
 	Pagination Pagination          `json:"pagination"`
 }
-type Fee struct {
-	Denom  string `json:"denom"`
-	Amount string `json:"amount"`
-}
-type Amount struct {
-	Denom  string `json:"denom"`
-	Amount string `json:"amount"`
-}
+type Fee = Coin
+type Amount = Coin
 type Content struct {
-	Name             string   `json:"name"`
-	UUID             string   `json:"uuid"`
-	Height           int      `json:"height"`
-	Msgname          string   `json:"msgName"`
-	Msgindex         int      `json:"msgIndex"`
-	Delegatoraddress string   `json:"delegatorAddress"`
-	Recipientaddress string   `json:"recipientAddress"`
-	Amount           []Amount `json:"amount"`
-	Txhash           string   `json:"txHash"`
-	Version          int      `json:"version"`
-	Validatoraddress string   `json:"validatorAddress"`
+	Name             string `json:"name"`
+	UUID             string `json:"uuid"`
+	Height           int    `json:"height"`
+	Msgname          string `json:"msgName"`
+	Msgindex         int    `json:"msgIndex"`
+	Fromaddress      string `json:"fromAddress"`
+	Toaddress        string `json:"toAddress"`
+	Delegatoraddress string `json:"delegatorAddress"`
+	Recipientaddress string `json:"recipientAddress"`
+	Amount           Coins  `json:"amount"`
+	Txhash           string `json:"txHash"`
+	Version          int    `json:"version"`
+	Validatoraddress string `json:"validatorAddress"`
 }
 
 type Messages struct {
@@ -0,0 +1,237 @@
+package lib_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/lib"
+	"github.com/igaskin/crypto-tracker/lib/explorertest"
+)
+
+const account = "cro1qx3y0ak0nl6anhe3dy0r7t5n7yqzmmg4mfyxwk"
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestGetAccountDecodesRecordedPayload(t *testing.T) {
+	server := explorertest.NewServer()
+	defer server.Close()
+	server.Respond("/accounts/"+account, readTestdata(t, "account.json"))
+
+	resp, err := server.ExplorerClient().GetAccount(context.Background(), &lib.GetAccountOpts{AccountID: account})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Result.Address != account {
+		t.Errorf("address = %q, want %q", resp.Result.Address, account)
+	}
+	for name, want := range map[string]struct {
+		coins []lib.Coin
+		cro   float64
+	}{
+		"balance":      {resp.Result.Balance, 1520},
+		"bonded":       {resp.Result.Bondedbalance, 10000},
+		"rewards":      {resp.Result.Totalrewards, 25.34000000523651891427},
+		"totalBalance": {resp.Result.Totalbalance, 11545.34000000523651891427},
+	} {
+		got, err := lib.SumCRO(want.coins)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if math.Abs(got-want.cro) > 1e-8 {
+			t.Errorf("%s = %v CRO, want %v", name, got, want.cro)
+		}
+	}
+}
+
+func TestGetAccountTransactionDecodesRecordedPayload(t *testing.T) {
+	server := explorertest.NewServer()
+	defer server.Close()
+	server.Respond("/accounts/"+account+"/transactions?limit=2&order=height.asc&page=1", readTestdata(t, "transactions.json"))
+
+	resp, err := server.ExplorerClient().GetAccountTransaction(context.Background(), &lib.GetAccountTransactionOpts{
+		Account: account,
+		Page:    1,
+		Limit:   2,
+		Order:   "height.asc",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (lib.Pagination{TotalRecord: 3, TotalPage: 2, CurrentPage: 1, Limit: 2}); resp.Pagination != want {
+		t.Errorf("pagination = %+v, want %+v", resp.Pagination, want)
+	}
+	if len(resp.Result) != 2 {
+		t.Fatalf("got %d transactions, want 2", len(resp.Result))
+	}
+
+	send := resp.Result[0]
+	if want := time.Date(2021, 5, 2, 10, 14, 7, 591203431, time.UTC); !send.Blocktime.Equal(want) {
+		t.Errorf("block time = %v, want %v", send.Blocktime, want)
+	}
+	if !send.Success || send.Blockheight != 1520311 || send.Gasused != 72125 {
+		t.Errorf("unexpected transaction %+v", send)
+	}
+	if fee, _ := lib.SumCRO(send.Fee); fee != 0.00005 {
+		t.Errorf("fee = %v CRO, want 0.00005", fee)
+	}
+	content := send.Messages[0].Content
+	if content.Msgname != "MsgSend" || content.Toaddress != account || content.Fromaddress == "" {
+		t.Errorf("unexpected MsgSend content %+v", content)
+	}
+	if amount, _ := lib.SumCRO(content.Amount); amount != 11600 {
+		t.Errorf("sent %v CRO, want 11600", amount)
+	}
+
+	// delegations report a single coin rather than a list
+	delegate := resp.Result[1].Messages[0].Content
+	if want := (lib.Coins{{Denom: "basecro", Amount: "1000000000000"}}); !reflect.DeepEqual(delegate.Amount, want) {
+		t.Errorf("delegated %+v, want %+v", delegate.Amount, want)
+	}
+	if delegate.Delegatoraddress != account || delegate.Validatoraddress == "" {
+		t.Errorf("unexpected MsgDelegate content %+v", delegate)
+	}
+}
+
+func TestListAccountTransactionsPages(t *testing.T) {
+	server := explorertest.NewServer()
+	defer server.Close()
+	server.AddAccount(lib.Result{Address: account})
+	var want []int
+	for height := 250; height > 0; height-- {
+		server.AddTransactions(account, lib.TransactionResult{Account: account, Blockheight: height})
+		want = append([]int{height}, want...)
+	}
+
+	transactions, err := server.ExplorerClient().ListAccountTransactions(context.Background(), account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for _, tx := range transactions {
+		got = append(got, tx.Blockheight)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got heights %v, want 1 to 250 in order", got)
+	}
+	wantRequests := []string{
+		"/accounts/" + account + "/transactions?limit=100&order=height.asc&page=1",
+		"/accounts/" + account + "/transactions?limit=100&order=height.asc&page=2",
+		"/accounts/" + account + "/transactions?limit=100&order=height.asc&page=3",
+	}
+	if got := server.Requests(); !reflect.DeepEqual(got, wantRequests) {
+		t.Errorf("requests = %q, want %q", got, wantRequests)
+	}
+}
+
+func TestListAccountTransactionsEmpty(t *testing.T) {
+	server := explorertest.NewServer()
+	defer server.Close()
+	server.AddAccount(lib.Result{Address: account})
+
+	transactions, err := server.ExplorerClient().ListAccountTransactions(context.Background(), account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 0 {
+		t.Errorf("got %d transactions, want none", len(transactions))
+	}
+}
+
+func TestExplorerErrors(t *testing.T) {
+	server := explorertest.NewServer()
+	defer server.Close()
+	server.AddAccount(lib.Result{Address: account})
+	for height := 1; height <= 150; height++ {
+		server.AddTransactions(account, lib.TransactionResult{Account: account, Blockheight: height})
+	}
+	server.Fail("page=2", http.StatusBadGateway)
+	client := server.ExplorerClient()
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		call   func() error
+		status int
+	}{
+		{"unknown account", func() error {
+			_, err := client.GetAccount(ctx, &lib.GetAccountOpts{AccountID: "cro1unknown"})
+			return err
+		}, http.StatusNotFound},
+		{"unknown account transactions", func() error {
+			_, err := client.GetAccountTransaction(ctx, &lib.GetAccountTransactionOpts{Account: "cro1unknown"})
+			return err
+		}, http.StatusNotFound},
+		{"invalid order", func() error {
+			_, err := client.GetAccountTransaction(ctx, &lib.GetAccountTransactionOpts{Account: account, Order: "time"})
+			return err
+		}, http.StatusBadRequest},
+		{"failure mid pagination", func() error {
+			_, err := client.ListAccountTransactions(ctx, account)
+			return err
+		}, http.StatusBadGateway},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.call()
+			var explorerErr *lib.ExplorerError
+			if !errors.As(err, &explorerErr) {
+				t.Fatalf("err = %v, want an ExplorerError", err)
+			}
+			if explorerErr.StatusCode != test.status {
+				t.Errorf("status = %d, want %d", explorerErr.StatusCode, test.status)
+			}
+			if explorerErr.Message == "" {
+				t.Error("the error body wasn't decoded")
+			}
+		})
+	}
+}
+
+func TestExplorerHonorsContext(t *testing.T) {
+	server := explorertest.NewServer()
+	defer server.Close()
+	server.AddAccount(lib.Result{Address: account})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := server.ExplorerClient().GetAccount(ctx, &lib.GetAccountOpts{AccountID: account}); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCoinCRO(t *testing.T) {
+	tests := []struct {
+		coin    lib.Coin
+		want    float64
+		wantErr bool
+	}{
+		{lib.Coin{Denom: "basecro", Amount: "123456789"}, 1.23456789, false},
+		{lib.Coin{Denom: "CRO", Amount: "2.5"}, 2.5, false},
+		{lib.Coin{Denom: "basecro", Amount: "many"}, 0, true},
+		{lib.Coin{Denom: "uatom", Amount: "1"}, 0, true},
+	}
+	for _, test := range tests {
+		got, err := test.coin.CRO()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%+v: err = %v, wantErr %v", test.coin, err, test.wantErr)
+		}
+		if got != test.want {
+			t.Errorf("%+v = %v CRO, want %v", test.coin, got, test.want)
+		}
+	}
+}
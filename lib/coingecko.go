@@ -0,0 +1,172 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// coinGeckoIDs maps asset symbols to CoinGecko coin ids.
+var coinGeckoIDs = map[string]string{
+	"BTC": "bitcoin",
+	"CRO": "crypto-com-chain",
+	"ETH": "ethereum",
+}
+
+type CoinGeckoClient struct {
+	// API endpoint
+	// default: https://api.coingecko.com/api/v3/
+	Server string
+
+	Client *http.Client
+}
+
+// Creates a new CoinGeckoClient, with reasonable defaults
+func NewCoinGeckoClient(server string) *CoinGeckoClient {
+	coinGeckoClient := CoinGeckoClient{
+		Server: server,
+	}
+	// ensure the server URL always has a trailing slash
+	if !strings.HasSuffix(coinGeckoClient.Server, "/") {
+		coinGeckoClient.Server += "/"
+	}
+	// create httpClient, if not already present
+	if coinGeckoClient.Client == nil {
+		coinGeckoClient.Client = &http.Client{}
+	}
+	return &coinGeckoClient
+}
+
+// SimplePrice returns the price of every coin id in every vs currency, keyed
+// by coin id then lower case currency.
+func (c *CoinGeckoClient) SimplePrice(ctx context.Context, opts *SimplePriceOpts) (SimplePriceResponse, error) {
+	query := url.Values{}
+	query.Set("ids", strings.Join(opts.IDs, ","))
+	query.Set("vs_currencies", strings.ToLower(strings.Join(opts.VsCurrencies, ",")))
+
+	var prices SimplePriceResponse
+	if err := c.get(ctx, "simple/price", query, &prices); err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+// Price returns the price of one unit of asset in fiat.
+func (c *CoinGeckoClient) Price(ctx context.Context, asset, fiat string) (float64, error) {
+	id, ok := coinGeckoIDs[strings.ToUpper(asset)]
+	if !ok {
+		return 0, fmt.Errorf("unsupported asset %q", asset)
+	}
+	prices, err := c.SimplePrice(ctx, &SimplePriceOpts{
+		IDs:          []string{id},
+		VsCurrencies: []string{fiat},
+	})
+	if err != nil {
+		return 0, err
+	}
+	price, ok := prices[id][strings.ToLower(fiat)]
+	if !ok {
+		return 0, fmt.Errorf("no %s price for %s", fiat, asset)
+	}
+	return price, nil
+}
+
+// MarketChartRange returns the prices, market caps and volumes of a coin id
+// between two times. CoinGecko picks the granularity from the length of the
+// range: minutely up to a day, hourly up to 90 days and daily beyond.
+func (c *CoinGeckoClient) MarketChartRange(ctx context.Context, opts *MarketChartRangeOpts) (*MarketChartResponse, error) {
+	query := url.Values{}
+	query.Set("vs_currency", strings.ToLower(opts.VsCurrency))
+	query.Set("from", strconv.FormatInt(opts.From.Unix(), 10))
+	query.Set("to", strconv.FormatInt(opts.To.Unix(), 10))
+
+	var chart MarketChartResponse
+	if err := c.get(ctx, "coins/"+url.PathEscape(opts.ID)+"/market_chart/range", query, &chart); err != nil {
+		return nil, err
+	}
+	return &chart, nil
+}
+
+// DailyPrices returns the last price of asset in fiat on every UTC day from
+// from to to, keyed by date ("2006-01-02"). Days CoinGecko has no price for
+// are left out.
+func (c *CoinGeckoClient) DailyPrices(ctx context.Context, asset, fiat string, from, to time.Time) (map[string]float64, error) {
+	id, ok := coinGeckoIDs[strings.ToUpper(asset)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported asset %q", asset)
+	}
+	day := 24 * time.Hour
+	chart, err := c.MarketChartRange(ctx, &MarketChartRangeOpts{
+		ID:         id,
+		VsCurrency: fiat,
+		From:       from.UTC().Truncate(day),
+		To:         to.UTC().Truncate(day).Add(day),
+	})
+	if err != nil {
+		return nil, err
+	}
+	prices := map[string]float64{}
+	latest := map[string]float64{}
+	for _, point := range chart.Prices {
+		at := time.Unix(0, int64(point[0])*int64(time.Millisecond)).UTC()
+		date := at.Format("2006-01-02")
+		// the samples aren't guaranteed to be sorted
+		if _, ok := latest[date]; !ok || point[0] >= latest[date] {
+			latest[date] = point[0]
+			prices[date] = point[1]
+		}
+	}
+	return prices, nil
+}
+
+func (c *CoinGeckoClient) get(ctx context.Context, operationPath string, query url.Values, v interface{}) error {
+	serverURL, err := url.Parse(c.Server)
+	if err != nil {
+		return err
+	}
+	operationURL := url.URL{
+		Path:     operationPath,
+		RawQuery: query.Encode(),
+	}
+	queryURL := serverURL.ResolveReference(&operationURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", queryURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("coingecko returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+type SimplePriceOpts struct {
+	IDs          []string
+	VsCurrencies []string
+}
+
+type SimplePriceResponse map[string]map[string]float64
+
+type MarketChartRangeOpts struct {
+	ID         string
+	VsCurrency string
+	From       time.Time
+	To         time.Time
+}
+
+// MarketChartResponse holds [unix milliseconds, value] pairs.
+type MarketChartResponse struct {
+	Prices       [][2]float64 `json:"prices"`
+	MarketCaps   [][2]float64 `json:"market_caps"`
+	TotalVolumes [][2]float64 `json:"total_volumes"`
+}
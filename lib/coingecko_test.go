@@ -0,0 +1,49 @@
+package lib_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/lib"
+)
+
+func TestDailyPrices(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(readTestdata(t, "market_chart_range.json"))
+	}))
+	defer server.Close()
+
+	client := lib.NewCoinGeckoClient(server.URL)
+	from := time.Date(2021, 3, 1, 9, 30, 0, 0, time.UTC)
+	to := time.Date(2021, 3, 3, 18, 0, 0, 0, time.UTC)
+	prices, err := client.DailyPrices(context.Background(), "cro", "USD", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the last sample of each day, even when they arrive out of order
+	want := map[string]float64{
+		"2021-03-01": 0.0655,
+		"2021-03-02": 0.0710,
+		"2021-03-03": 0.0689,
+	}
+	if !reflect.DeepEqual(prices, want) {
+		t.Errorf("prices = %v, want %v", prices, want)
+	}
+	// whole days, up to the end of the last one
+	wantRequests := []string{"/coins/crypto-com-chain/market_chart/range?from=1614556800&to=1614816000&vs_currency=usd"}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("requests = %q, want %q", requests, wantRequests)
+	}
+
+	if _, err := client.DailyPrices(context.Background(), "DOGE", "USD", from, to); err == nil {
+		t.Error("expected an unsupported asset to fail")
+	}
+}
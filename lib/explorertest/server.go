@@ -0,0 +1,206 @@
+// Package explorertest provides an httptest stub of the crypto.org explorer
+// endpoints used by lib.ExplorerClient.
+package explorertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/igaskin/crypto-tracker/lib"
+)
+
+// DefaultLimit is the page size used when a request doesn't set one.
+const DefaultLimit = 20
+
+// Server serves
+//
+//	GET /accounts/{account}
+//	GET /accounts/{account}/transactions?page=&limit=&order=
+//
+// from accounts and transactions added by the test. Unknown accounts get a
+// 404, Respond replays recorded payloads and Fail injects error responses.
+type Server struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	accounts     map[string]lib.Result
+	transactions map[string][]lib.TransactionResult
+	failures     []failure
+	canned       map[string][]byte
+	requests     []string
+}
+
+type failure struct {
+	match  string
+	status int
+}
+
+// NewServer starts an empty stub. Callers should Close it.
+func NewServer() *Server {
+	s := &Server{
+		accounts:     map[string]lib.Result{},
+		transactions: map[string][]lib.TransactionResult{},
+		canned:       map[string][]byte{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// ExplorerClient returns an explorer client talking to the stub.
+func (s *Server) ExplorerClient() *lib.ExplorerClient {
+	client := lib.NewExplorerClient(s.URL + "/")
+	client.Client = s.Client()
+	return client
+}
+
+// AddAccount serves an account's balances, keyed by its address.
+func (s *Server) AddAccount(account lib.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts[account.Address] = account
+	if _, ok := s.transactions[account.Address]; !ok {
+		s.transactions[account.Address] = nil
+	}
+}
+
+// AddTransactions appends to an account's transaction history.
+func (s *Server) AddTransactions(account string, transactions ...lib.TransactionResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transactions[account] = append(s.transactions[account], transactions...)
+}
+
+// Respond serves body, e.g. a recorded explorer payload, for requests to
+// exactly path and query, such as "/accounts/cro1.../transactions?page=1".
+func (s *Server) Respond(pathAndQuery string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.canned[pathAndQuery] = body
+}
+
+// Fail makes every request whose path and query contain match, e.g.
+// "page=2", respond with status.
+func (s *Server) Fail(match string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{match: match, status: status})
+}
+
+// Requests lists the path and query of every request served so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	for _, f := range s.failures {
+		if strings.Contains(r.URL.RequestURI(), f.match) {
+			writeError(w, f.status, http.StatusText(f.status))
+			return
+		}
+	}
+	if body, ok := s.canned[r.URL.RequestURI()]; ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "accounts":
+		account, ok := s.accounts[parts[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "account not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, lib.GetAccountResponse{Result: account})
+	case len(parts) == 3 && parts[0] == "accounts" && parts[2] == "transactions":
+		transactions, ok := s.transactions[parts[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "account not found")
+			return
+		}
+		resp, err := page(transactions, r.URL.Query())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+type badRequest string
+
+func (e badRequest) Error() string {
+	return string(e)
+}
+
+func page(transactions []lib.TransactionResult, query url.Values) (*lib.GetAccountTransactionResponse, error) {
+	number, limit := 1, DefaultLimit
+	var err error
+	if v := query.Get("page"); v != "" {
+		if number, err = strconv.Atoi(v); err != nil || number < 1 {
+			return nil, badRequest("invalid page " + v)
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			return nil, badRequest("invalid limit " + v)
+		}
+	}
+
+	sorted := append([]lib.TransactionResult(nil), transactions...)
+	switch query.Get("order") {
+	case "", "height.asc":
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Blockheight < sorted[j].Blockheight })
+	case "height.desc":
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Blockheight > sorted[j].Blockheight })
+	default:
+		return nil, badRequest("invalid order " + query.Get("order"))
+	}
+
+	resp := &lib.GetAccountTransactionResponse{
+		Result: []lib.TransactionResult{},
+		Pagination: lib.Pagination{
+			TotalRecord: len(sorted),
+			TotalPage:   (len(sorted) + limit - 1) / limit,
+			CurrentPage: number,
+			Limit:       limit,
+		},
+	}
+	start := (number - 1) * limit
+	if start < len(sorted) {
+		end := start + limit
+		if end > len(sorted) {
+			end = len(sorted)
+		}
+		resp.Result = sorted[start:end]
+	}
+	return resp, nil
+}
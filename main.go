@@ -16,11 +16,14 @@ limitations under the License.
 package main
 
 import (
+	"os"
+
 	"github.com/igaskin/crypto-tracker/cmd"
-	"github.com/spf13/cobra"
 )
 
 func main() {
-	// cmd.Execute()
-	cobra.CheckErr(cmd.NewRootCommand().Execute())
+	// cobra has already printed the error
+	if err := cmd.NewRootCommand().Execute(); err != nil {
+		os.Exit(cmd.ExitCode(err))
+	}
 }
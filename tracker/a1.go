@@ -0,0 +1,68 @@
+package tracker
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ColumnIndex converts a column name such as "A" or "AA" into its zero based
+// index.
+func ColumnIndex(name string) (int64, error) {
+	if name == "" {
+		return 0, fmt.Errorf("empty column name")
+	}
+	var index int64
+	for _, r := range strings.ToUpper(name) {
+		if r < 'A' || r > 'Z' {
+			return 0, fmt.Errorf("invalid column name %q", name)
+		}
+		index = index*26 + int64(r-'A'+1)
+	}
+	return index - 1, nil
+}
+
+// ColumnName converts a zero based column index into its name, so 0 is "A"
+// and 26 is "AA".
+func ColumnName(index int64) string {
+	var name []byte
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = append([]byte{byte('A' + (index-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+// Cell formats the A1 reference of a zero based column and a one based row.
+func Cell(column, row int64) string {
+	return ColumnName(column) + strconv.FormatInt(row, 10)
+}
+
+// SheetRange prefixes an A1 reference with a sheet name, quoting it unless
+// it is a plain identifier that can't be mistaken for a cell.
+func SheetRange(sheet, ref string) string {
+	if !plainSheetName.MatchString(sheet) || a1Pattern.MatchString(sheet) || r1c1Pattern.MatchString(sheet) {
+		sheet = "'" + strings.ReplaceAll(sheet, "'", "''") + "'"
+	}
+	return sheet + "!" + ref
+}
+
+var (
+	plainSheetName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	a1Pattern      = regexp.MustCompile(`^([A-Za-z]+)([1-9][0-9]*)$`)
+	r1c1Pattern    = regexp.MustCompile(`^[Rr][0-9]*[Cc][0-9]*$`)
+)
+
+// ParseCell splits an A1 reference such as "AA10" into a zero based column
+// and a one based row.
+func ParseCell(ref string) (column, row int64, err error) {
+	match := a1Pattern.FindStringSubmatch(ref)
+	if match == nil {
+		return 0, 0, fmt.Errorf("invalid A1 cell %q", ref)
+	}
+	if column, err = ColumnIndex(match[1]); err != nil {
+		return 0, 0, err
+	}
+	row, err = strconv.ParseInt(match[2], 10, 64)
+	return column, row, err
+}
@@ -0,0 +1,84 @@
+package tracker_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+)
+
+func TestColumnNames(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		index int64
+	}{
+		{"A", 0},
+		{"Z", 25},
+		{"AA", 26},
+		{"AZ", 51},
+		{"BA", 52},
+		{"ZZ", 701},
+		{"AAA", 702},
+	} {
+		if got := tracker.ColumnName(test.index); got != test.name {
+			t.Errorf("ColumnName(%d) = %s, want %s", test.index, got, test.name)
+		}
+		if got, err := tracker.ColumnIndex(test.name); err != nil || got != test.index {
+			t.Errorf("ColumnIndex(%s) = %d, %v, want %d", test.name, got, err, test.index)
+		}
+	}
+	if got, err := tracker.ColumnIndex("ab"); err != nil || got != 27 {
+		t.Errorf("ColumnIndex(ab) = %d, %v, want 27", got, err)
+	}
+	for _, name := range []string{"", "A1", "Ä", "A-B", " A"} {
+		if _, err := tracker.ColumnIndex(name); err == nil {
+			t.Errorf("ColumnIndex(%q) succeeded", name)
+		}
+	}
+}
+
+func TestParseCell(t *testing.T) {
+	for _, test := range []struct {
+		ref         string
+		column, row int64
+	}{
+		{"A1", 0, 1},
+		{"c5", 2, 5},
+		{"AA10", 26, 10},
+		{"ZZ1000", 701, 1000},
+	} {
+		column, row, err := tracker.ParseCell(test.ref)
+		if err != nil || column != test.column || row != test.row {
+			t.Errorf("ParseCell(%s) = %d, %d, %v, want %d, %d", test.ref, column, row, err, test.column, test.row)
+		}
+		if cell := tracker.Cell(column, row); cell != strings.ToUpper(test.ref) {
+			t.Errorf("Cell(%d, %d) = %s, want %s", column, row, cell, strings.ToUpper(test.ref))
+		}
+	}
+	for _, ref := range []string{"", "A", "10", "A0", "A01", "1A", "A1:B2", "Sheet1!A1", "A-1"} {
+		if _, _, err := tracker.ParseCell(ref); err == nil {
+			t.Errorf("ParseCell(%q) succeeded", ref)
+		}
+	}
+}
+
+func TestSheetRange(t *testing.T) {
+	for sheet, want := range map[string]string{
+		"ROI":         "ROI!A1",
+		"History_2":   "History_2!A1",
+		"My Sheet":    "'My Sheet'!A1",
+		"Bob's":       "'Bob''s'!A1",
+		"a!b":         "'a!b'!A1",
+		"crypto-2021": "'crypto-2021'!A1",
+		"2021":        "'2021'!A1",
+		"1st":         "'1st'!A1",
+		"Prix €":      "'Prix €'!A1",
+		"AB12":        "'AB12'!A1",
+		"R1C1":        "'R1C1'!A1",
+		"":            "''!A1",
+	} {
+		if got := tracker.SheetRange(sheet, "A1"); got != want {
+			t.Errorf("SheetRange(%q) = %s, want %s", sheet, got, want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package tracker
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Binance trade history exports list one fill per row. Current exports
+// suffix every amount with its asset, such as "1,000CRO"; older ones name
+// the market, such as CROUSDT, and list the fee asset in a column of its own.
+
+// binanceColumns are the normalized header names of the fields of a Binance
+// trade history export.
+var binanceColumns = map[string][]string{
+	"time":         {"dateutc"},
+	"pair":         {"pair", "market"},
+	"side":         {"side", "type"},
+	"price":        {"price"},
+	"executed":     {"executed"},
+	"amount":       {"amount"},
+	"total":        {"total"},
+	"fee":          {"fee"},
+	"fee currency": {"feecoin"},
+}
+
+// the fields of the current and the older exports
+var (
+	binanceFields       = []string{"time", "pair", "side", "executed", "amount", "fee"}
+	binanceLegacyFields = []string{"time", "pair", "side", "price", "amount", "total", "fee", "fee currency"}
+)
+
+// binanceQuotes are the quote assets markets are split on, four letter ones
+// first so CROBUSD splits as CRO and BUSD rather than CROB and USD.
+var binanceQuotes = []string{"USDT", "BUSD", "USDC", "TUSD", "BTC", "ETH", "BNB", "EUR", "USD", "GBP"}
+
+// binanceAmount is an amount suffixed with an asset starting with a letter,
+// for assets the market doesn't name.
+var binanceAmount = regexp.MustCompile(`^([\d.,]+)\s*([A-Za-z][A-Za-z0-9]*)$`)
+
+// binanceNumber is an amount without its asset.
+var binanceNumber = regexp.MustCompile(`^[\d.,]+$`)
+
+// binanceFormat is a Binance trade history export.
+type binanceFormat struct{}
+
+func (binanceFormat) Name() string {
+	return "binance"
+}
+
+func (binanceFormat) Detect(header []string) bool {
+	c := locateColumns(header, binanceColumns)
+	return c.has(binanceFields...) || c.has(binanceLegacyFields...)
+}
+
+func (binanceFormat) Parser(header []string) (Parser, error) {
+	c := locateColumns(header, binanceColumns)
+	if c.has(binanceFields...) {
+		return recordParser(c.binanceTrade), nil
+	}
+	return recordParser(c.binanceLegacyTrade), nil
+}
+
+// binanceTrade parses a fill of a current export. Amounts are split on the
+// assets of the market first, since an asset may start with a digit.
+func (c columns) binanceTrade(record []string) ([]Transaction, error) {
+	base, quote, err := c.binanceMarket(record)
+	if err != nil {
+		return nil, err
+	}
+	known := append([]string{base, quote}, binanceQuotes...)
+	var amounts [3]float64
+	var assets [3]string
+	for i, field := range []string{"executed", "amount", "fee"} {
+		value, err := c.get(record, field)
+		if err != nil {
+			return nil, err
+		}
+		if amounts[i], assets[i], err = splitBinanceAmount(field, value, known); err != nil {
+			return nil, err
+		}
+	}
+	return c.binanceFill(record, assets[0], amounts[0], assets[1], amounts[1], assets[2], amounts[2])
+}
+
+// splitBinanceAmount splits an amount from the asset it is suffixed with,
+// trying the known assets before guessing, so 101INCH is 10 1INCH.
+func splitBinanceAmount(field, value string, known []string) (float64, string, error) {
+	value = strings.TrimSpace(value)
+	upper := strings.ToUpper(value)
+	for _, asset := range known {
+		if !strings.HasSuffix(upper, asset) {
+			continue
+		}
+		if number := strings.TrimSpace(value[:len(value)-len(asset)]); binanceNumber.MatchString(number) {
+			amount, err := parseNumber(field, number)
+			return amount, asset, err
+		}
+	}
+	match := binanceAmount.FindStringSubmatch(value)
+	if match == nil {
+		return 0, "", fmt.Errorf("invalid %s %q, expected an amount followed by its asset", field, value)
+	}
+	amount, err := parseNumber(field, match[1])
+	return amount, strings.ToUpper(match[2]), err
+}
+
+// binanceMarket splits the market of a fill, such as CROUSDT, into its base
+// and quote assets.
+func (c columns) binanceMarket(record []string) (base, quote string, err error) {
+	market, err := c.get(record, "pair")
+	if err != nil {
+		return "", "", err
+	}
+	market = strings.ToUpper(market)
+	for _, q := range binanceQuotes {
+		if strings.HasSuffix(market, q) && len(market) > len(q) {
+			return strings.TrimSuffix(market, q), q, nil
+		}
+	}
+	return "", "", fmt.Errorf("can't tell the quote asset of market %q", market)
+}
+
+// binanceLegacyTrade parses a fill of an older export.
+func (c columns) binanceLegacyTrade(record []string) ([]Transaction, error) {
+	base, quote, err := c.binanceMarket(record)
+	if err != nil {
+		return nil, err
+	}
+	var numbers [3]float64
+	for i, field := range []string{"amount", "total", "fee"} {
+		if numbers[i], err = c.number(record, field); err != nil {
+			return nil, err
+		}
+	}
+	feeCurrency, err := c.get(record, "fee currency")
+	if err != nil {
+		return nil, err
+	}
+	return c.binanceFill(record, base, numbers[0], quote, numbers[1], strings.ToUpper(feeCurrency), numbers[2])
+}
+
+// binanceFill turns a fill of quantity base for total quote into the trade
+// and its fee.
+func (c columns) binanceFill(record []string, base string, quantity float64, quote string, total float64, feeCurrency string, fee float64) ([]Transaction, error) {
+	timestamp, err := c.timestamp(record, "time")
+	if err != nil {
+		return nil, err
+	}
+	side, err := c.get(record, "side")
+	if err != nil {
+		return nil, err
+	}
+	quantity, total = math.Abs(quantity), math.Abs(total)
+	var trade Transaction
+	switch strings.ToUpper(side) {
+	case "BUY":
+		trade = newTrade(timestamp, tradeDescription(quote, base), quote, total, base, quantity)
+	case "SELL":
+		trade = newTrade(timestamp, tradeDescription(base, quote), base, quantity, quote, total)
+	default:
+		return nil, fmt.Errorf("invalid side %q, expected BUY or SELL", side)
+	}
+	transactions := []Transaction{trade}
+	if fee == 0 {
+		return transactions, nil
+	}
+	charge := newFee(timestamp, feeCurrency, fee)
+	// fees in the base are valued at the trade's price
+	if fiat, ok := fiatStablecoins[quote]; ok && feeCurrency == base && quantity != 0 {
+		charge.setNative(fiat, math.Abs(fee)*total/quantity)
+	}
+	return append(transactions, charge), nil
+}
@@ -0,0 +1,122 @@
+package tracker
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Coinbase transaction history reports start with a few lines of preamble,
+// then list one transaction per row valued in the account's fiat. Buys and
+// sells are totalled with their fees, so fees aren't separate transactions.
+
+// coinbaseColumns are the normalized header names of the fields of a
+// Coinbase transaction history report. Newer reports add an ID column and
+// rename the price and fee columns.
+var coinbaseColumns = map[string][]string{
+	"timestamp":      {"timestamp"},
+	"type":           {"transactiontype"},
+	"asset":          {"asset"},
+	"quantity":       {"quantitytransacted"},
+	"price currency": {"spotpricecurrency", "pricecurrency"},
+	"price":          {"spotpriceattransaction", "priceattransaction"},
+	"subtotal":       {"subtotal"},
+	"total":          {"totalinclusiveoffees", "totalinclusiveoffeesandorspread"},
+	"notes":          {"notes"},
+}
+
+var coinbaseFields = []string{"timestamp", "type", "asset", "quantity", "price currency", "price", "total"}
+
+// coinbaseConversion is the note of a Convert transaction, such as
+// "Converted 1,000 CRO to 0.0032 BTC".
+var coinbaseConversion = regexp.MustCompile(`^Converted ([\d.,]+) (\S+) to ([\d.,]+) (\S+)`)
+
+// coinbaseIncome maps the types of transactions paying out crypto to their
+// kind and description.
+var coinbaseIncome = map[string][2]string{
+	"STAKING INCOME":   {ExchangeStakingRewardKind, ExchangeStakingReward},
+	"INFLATION REWARD": {ExchangeStakingRewardKind, ExchangeStakingReward},
+	"REWARDS INCOME":   {ExchangeInterestKind, ExchangeInterest},
+	"INTEREST":         {ExchangeInterestKind, ExchangeInterest},
+	"COINBASE EARN":    {ExchangeRewardKind, ExchangeReward},
+	"LEARNING REWARD":  {ExchangeRewardKind, ExchangeReward},
+}
+
+// coinbaseFormat is a Coinbase transaction history report.
+type coinbaseFormat struct{}
+
+func (coinbaseFormat) Name() string {
+	return "coinbase"
+}
+
+func (coinbaseFormat) Detect(header []string) bool {
+	return locateColumns(header, coinbaseColumns).has(coinbaseFields...)
+}
+
+func (coinbaseFormat) Parser(header []string) (Parser, error) {
+	return recordParser(locateColumns(header, coinbaseColumns).coinbaseTransaction), nil
+}
+
+func (c columns) coinbaseTransaction(record []string) ([]Transaction, error) {
+	timestamp, err := c.timestamp(record, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+	var text [4]string
+	for i, field := range []string{"type", "asset", "price currency", "notes"} {
+		if text[i], err = c.get(record, field); err != nil {
+			return nil, err
+		}
+	}
+	kind, asset, fiat, notes := strings.ToUpper(text[0]), strings.ToUpper(text[1]), strings.ToUpper(text[2]), text[3]
+	var numbers [4]float64
+	for i, field := range []string{"quantity", "price", "subtotal", "total"} {
+		if numbers[i], err = c.number(record, field); err != nil {
+			return nil, err
+		}
+	}
+	quantity, price, subtotal, total := math.Abs(numbers[0]), numbers[1], math.Abs(numbers[2]), math.Abs(numbers[3])
+	if subtotal == 0 {
+		subtotal = quantity * price
+	}
+
+	// movements are valued at the spot price
+	movement := func(description, kind string, amount float64) []Transaction {
+		t := newMovement(timestamp, description, kind, asset, amount)
+		t.setNative(fiat, subtotal)
+		return []Transaction{t}
+	}
+	// trades are valued at their total, in whatever fiat the account uses
+	trade := func(t Transaction) []Transaction {
+		t.setNative(fiat, total)
+		return []Transaction{t}
+	}
+	switch kind {
+	case "BUY", "ADVANCED TRADE BUY":
+		return trade(newTrade(timestamp, string(ExchangeBuy), fiat, total, asset, quantity)), nil
+	case "SELL", "ADVANCED TRADE SELL":
+		return trade(newTrade(timestamp, ExchangeSell, asset, quantity, fiat, total)), nil
+	case "SEND":
+		return movement(ExchangeWithdrawal, ExchangeWithdrawalKind, -quantity), nil
+	case "RECEIVE":
+		return movement(ExchangeDeposit, ExchangeDepositKind, quantity), nil
+	case "CONVERT":
+		match := coinbaseConversion.FindStringSubmatch(notes)
+		if match == nil {
+			return nil, fmt.Errorf("can't tell what %s was converted to from the notes %q", asset, notes)
+		}
+		to := strings.ToUpper(match[4])
+		toAmount, err := parseNumber("converted amount", match[3])
+		if err != nil {
+			return nil, err
+		}
+		conversion := newTrade(timestamp, ExchangeConvert, asset, quantity, to, toAmount)
+		conversion.setNative(fiat, subtotal)
+		return []Transaction{conversion}, nil
+	}
+	if income, ok := coinbaseIncome[kind]; ok {
+		return movement(income[1], income[0], quantity), nil
+	}
+	return nil, fmt.Errorf("unknown transaction type %q", text[0])
+}
@@ -0,0 +1,169 @@
+package tracker
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Crypto.com Exchange exports don't share the app's layout. The trade history
+// lists one fill per row, with the instrument as BASE_QUOTE, and the deposit
+// and withdrawal history lists transfers in and out of the exchange. Every
+// fee is a transaction of its own.
+
+// exchangeColumns are the normalized header names of the fields of Crypto.com
+// Exchange exports, which name them in title case, such as "Fee Currency",
+// or in snake case, such as "fee_currency".
+var exchangeColumns = map[string][]string{
+	"time":         {"timeutc", "createtimeutc", "time"},
+	"instrument":   {"instrument", "instrumentname", "symbol"},
+	"side":         {"side"},
+	"price":        {"price", "tradedprice"},
+	"quantity":     {"quantity", "tradedquantity"},
+	"fee":          {"fee"},
+	"fee currency": {"feecurrency"},
+	"currency":     {"currency", "coin"},
+	"amount":       {"amount"},
+	"type":         {"type", "transactiontype"},
+	"status":       {"status"},
+}
+
+// the fields every row of an export needs
+var (
+	exchangeTradeFields    = []string{"time", "instrument", "side", "price", "quantity", "fee", "fee currency"}
+	exchangeTransferFields = []string{"time", "currency", "amount", "type"}
+)
+
+// statuses of transfers that never moved any funds
+var exchangeIncompleteStatuses = map[string]bool{
+	"PENDING":   true,
+	"FAILED":    true,
+	"CANCELLED": true,
+	"CANCELED":  true,
+	"REJECTED":  true,
+}
+
+// exchangeFormat is a Crypto.com Exchange trade, deposit or withdrawal
+// history export.
+type exchangeFormat struct{}
+
+func (exchangeFormat) Name() string {
+	return "crypto.com exchange"
+}
+
+func (exchangeFormat) Detect(header []string) bool {
+	c := locateColumns(header, exchangeColumns)
+	return c.has(exchangeTradeFields...) || c.has(exchangeTransferFields...)
+}
+
+func (exchangeFormat) Parser(header []string) (Parser, error) {
+	c := locateColumns(header, exchangeColumns)
+	if c.has(exchangeTradeFields...) {
+		return recordParser(c.exchangeTrade), nil
+	}
+	return recordParser(c.exchangeTransfer), nil
+}
+
+// exchangeTrade turns a fill into the trade and, when it charged one, its
+// fee.
+func (c columns) exchangeTrade(record []string) ([]Transaction, error) {
+	timestamp, err := c.timestamp(record, "time")
+	if err != nil {
+		return nil, err
+	}
+	instrument, err := c.get(record, "instrument")
+	if err != nil {
+		return nil, err
+	}
+	assets := strings.Split(strings.ToUpper(instrument), "_")
+	if len(assets) != 2 || assets[0] == "" || assets[1] == "" {
+		return nil, fmt.Errorf("invalid instrument %q, expected BASE_QUOTE", instrument)
+	}
+	base, quote := assets[0], assets[1]
+	side, err := c.get(record, "side")
+	if err != nil {
+		return nil, err
+	}
+	var fields [3]float64
+	for i, field := range []string{"price", "quantity", "fee"} {
+		if fields[i], err = c.number(record, field); err != nil {
+			return nil, err
+		}
+	}
+	price, quantity, fee := math.Abs(fields[0]), math.Abs(fields[1]), fields[2]
+	feeCurrency, err := c.get(record, "fee currency")
+	if err != nil {
+		return nil, err
+	}
+	feeCurrency = strings.ToUpper(feeCurrency)
+
+	total := price * quantity
+	var trade Transaction
+	switch strings.ToUpper(side) {
+	case "BUY":
+		trade = newTrade(timestamp, string(ExchangeBuy), quote, total, base, quantity)
+	case "SELL":
+		trade = newTrade(timestamp, ExchangeSell, base, quantity, quote, total)
+	default:
+		return nil, fmt.Errorf("invalid side %q, expected BUY or SELL", side)
+	}
+	transactions := []Transaction{trade}
+	if fee == 0 {
+		return transactions, nil
+	}
+	charge := newFee(timestamp, feeCurrency, fee)
+	// fees in the base are valued at the trade's price
+	if fiat, ok := fiatStablecoins[quote]; ok && feeCurrency == base {
+		charge.setNative(fiat, math.Abs(fee)*price)
+	}
+	return append(transactions, charge), nil
+}
+
+// exchangeTransfer turns a deposit or withdrawal into a transaction and,
+// for withdrawals charging one, its fee. Transfers that never completed are
+// left out.
+func (c columns) exchangeTransfer(record []string) ([]Transaction, error) {
+	status, err := c.get(record, "status")
+	if err != nil {
+		return nil, err
+	}
+	if exchangeIncompleteStatuses[strings.ToUpper(status)] {
+		return nil, nil
+	}
+	timestamp, err := c.timestamp(record, "time")
+	if err != nil {
+		return nil, err
+	}
+	currency, err := c.get(record, "currency")
+	if err != nil {
+		return nil, err
+	}
+	currency = strings.ToUpper(currency)
+	amount, err := c.number(record, "amount")
+	if err != nil {
+		return nil, err
+	}
+	fee, err := c.number(record, "fee")
+	if err != nil {
+		return nil, err
+	}
+	kind, err := c.get(record, "type")
+	if err != nil {
+		return nil, err
+	}
+
+	var transfer Transaction
+	switch strings.ToUpper(kind) {
+	case "DEPOSIT":
+		transfer = newMovement(timestamp, ExchangeDeposit, ExchangeDepositKind, currency, math.Abs(amount))
+	case "WITHDRAWAL", "WITHDRAW":
+		transfer = newMovement(timestamp, ExchangeWithdrawal, ExchangeWithdrawalKind, currency, -math.Abs(amount))
+	default:
+		return nil, fmt.Errorf("invalid type %q, expected DEPOSIT or WITHDRAWAL", kind)
+	}
+	transactions := []Transaction{transfer}
+	if fee == 0 {
+		return transactions, nil
+	}
+	return append(transactions, newFee(timestamp, currency, fee)), nil
+}
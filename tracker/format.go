@@ -0,0 +1,292 @@
+package tracker
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a csv export layout, recognized by its header row.
+type Format interface {
+	// Name identifies the format in messages, e.g. "kraken"
+	Name() string
+	// Detect reports whether a header row is the format's
+	Detect(header []string) bool
+	// Parser returns a parser for the records following the header
+	Parser(header []string) (Parser, error)
+}
+
+// Parser turns the records of an export into transactions.
+type Parser interface {
+	// Parse parses a record, returning the transactions it completes
+	Parse(record []string) ([]Transaction, error)
+	// Flush returns the transactions still pending at the end of the export
+	Flush() ([]Transaction, error)
+}
+
+// formats are tried in order, so formats with a more specific header come
+// first. The Crypto.com App export is also parsed without a header.
+var formats = []Format{
+	coinbaseFormat{},
+	appFormat{},
+	krakenFormat{},
+	binanceFormat{},
+	exchangeFormat{},
+}
+
+// RegisterFormat adds a format ParseTransactions recognizes. It is tried
+// before the formats registered earlier and the built in ones, and replaces
+// any format of the same name.
+func RegisterFormat(f Format) {
+	registered := []Format{f}
+	for _, existing := range formats {
+		if existing.Name() != f.Name() {
+			registered = append(registered, existing)
+		}
+	}
+	formats = registered
+}
+
+// DetectFormat finds the format a header row belongs to.
+func DetectFormat(header []string) (Format, bool) {
+	for _, f := range formats {
+		if f.Detect(header) {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// FormatNames lists the formats ParseTransactions recognizes.
+func FormatNames() []string {
+	var names []string
+	for _, f := range formats {
+		names = append(names, f.Name())
+	}
+	return names
+}
+
+// transaction kinds of exchange exports, whichever exchange they come from
+const (
+	ExchangeTradeKind         = "exchange_trade"
+	ExchangeFeeKind           = "exchange_fee"
+	ExchangeDepositKind       = "exchange_deposit"
+	ExchangeWithdrawalKind    = "exchange_withdrawal"
+	ExchangeTransferKind      = "exchange_transfer"
+	ExchangeStakingRewardKind = "exchange_staking_reward"
+	ExchangeInterestKind      = "exchange_interest"
+	ExchangeRewardKind        = "exchange_reward"
+	ExchangeAdjustmentKind    = "exchange_adjustment"
+)
+
+// ExchangeBuy is the description of fiat or a stablecoin spent on crypto on
+// an exchange.
+const ExchangeBuy PurchaseEvent = "Exchange Buy"
+
+// descriptions of the other exchange transactions
+const (
+	ExchangeSell          = "Exchange Sell"
+	ExchangeConvert       = "Exchange Convert"
+	ExchangeFee           = "Exchange Fee"
+	ExchangeDeposit       = "Exchange Deposit"
+	ExchangeWithdrawal    = "Exchange Withdrawal"
+	ExchangeTransfer      = "Exchange Transfer"
+	ExchangeStakingReward = "Exchange Staking Reward"
+	ExchangeInterest      = "Exchange Interest"
+	ExchangeReward        = "Exchange Reward"
+	ExchangeAdjustment    = "Exchange Adjustment"
+)
+
+// stablecoins are valued as the fiat they track
+var fiatStablecoins = map[string]string{
+	"USD":  "USD",
+	"USDT": "USD",
+	"USDC": "USD",
+	"BUSD": "USD",
+	"EUR":  "EUR",
+}
+
+// newTrade records fromAmount of one asset spent on toAmount of another,
+// valued in fiat when either side is fiat or a stablecoin.
+func newTrade(timestamp time.Time, description string, from string, fromAmount float64, to string, toAmount float64) Transaction {
+	t := Transaction{
+		Timestamp:   timestamp,
+		Description: description,
+		Currency:    from,
+		Amount:      -fromAmount,
+		ToCurrency:  to,
+		ToAmount:    toAmount,
+		Kind:        ExchangeTradeKind,
+	}
+	if fiat, ok := fiatStablecoins[from]; ok {
+		t.setNative(fiat, fromAmount)
+	} else if fiat, ok := fiatStablecoins[to]; ok {
+		t.setNative(fiat, toAmount)
+	}
+	return t
+}
+
+// tradeDescription describes a trade by what was spent and bought.
+func tradeDescription(from, to string) string {
+	_, fromFiat := fiatStablecoins[from]
+	_, toFiat := fiatStablecoins[to]
+	switch {
+	case fromFiat && !toFiat:
+		return string(ExchangeBuy)
+	case toFiat && !fromFiat:
+		return ExchangeSell
+	}
+	return ExchangeConvert
+}
+
+// newMovement records amount of an asset added to, or when negative taken
+// from, the account, valued in fiat when it is fiat or a stablecoin.
+func newMovement(timestamp time.Time, description, kind, currency string, amount float64) Transaction {
+	t := Transaction{
+		Timestamp:   timestamp,
+		Description: description,
+		Currency:    currency,
+		Amount:      amount,
+		Kind:        kind,
+	}
+	if fiat, ok := fiatStablecoins[currency]; ok {
+		t.setNative(fiat, math.Abs(amount))
+	}
+	return t
+}
+
+// newFee records a fee charged in currency.
+func newFee(timestamp time.Time, currency string, fee float64) Transaction {
+	return newMovement(timestamp, ExchangeFee, ExchangeFeeKind, currency, -math.Abs(fee))
+}
+
+// setNative values the transaction in fiat, which for USD is also its USD
+// value.
+func (t *Transaction) setNative(fiat string, amount float64) {
+	t.NativeCurrency, t.NativeAmount = fiat, amount
+	if fiat == "USD" {
+		t.NativeAmountUSD = amount
+	}
+}
+
+// normalizeColumnName reduces a header name to lower case letters and
+// digits, so "Fee Currency" and "fee_currency" compare equal and a byte order
+// mark is dropped.
+func normalizeColumnName(name string) string {
+	name = strings.ToLower(name)
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// columns is the index of every field of a format in the records of an
+// export, located by header name.
+type columns map[string]int
+
+// locateColumns finds the fields in a header row. Each field lists the
+// normalized header names it goes by; the first column matching one wins.
+func locateColumns(header []string, names map[string][]string) columns {
+	c := columns{}
+	for i, name := range header {
+		name = normalizeColumnName(name)
+		for field, aliases := range names {
+			if _, ok := c[field]; ok {
+				continue
+			}
+			for _, alias := range aliases {
+				if name == alias {
+					c[field] = i
+				}
+			}
+		}
+	}
+	return c
+}
+
+// has reports whether every field has a column.
+func (c columns) has(fields ...string) bool {
+	for _, field := range fields {
+		if _, ok := c[field]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// get returns the trimmed value of a field, empty when the export doesn't
+// have it.
+func (c columns) get(record []string, field string) (string, error) {
+	i, ok := c[field]
+	if !ok {
+		return "", nil
+	}
+	if i >= len(record) {
+		return "", fmt.Errorf("expected at least %d columns, got %d", i+1, len(record))
+	}
+	return strings.TrimSpace(record[i]), nil
+}
+
+// number parses a field as a number, 0 when it is empty. Currency symbols
+// and thousands separators are ignored.
+func (c columns) number(record []string, field string) (float64, error) {
+	value, err := c.get(record, field)
+	if err != nil {
+		return 0, err
+	}
+	return parseNumber(field, value)
+}
+
+func parseNumber(field, value string) (float64, error) {
+	value = strings.NewReplacer(",", "", "$", "", "€", "", "£", "").Replace(value)
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", field, err)
+	}
+	return number, nil
+}
+
+// timestamp parses a field as a UTC time.
+func (c columns) timestamp(record []string, field string) (time.Time, error) {
+	value, err := c.get(record, field)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseTimestamp(value)
+}
+
+// timestampLayouts are the time formats exports use, all in UTC unless they
+// say otherwise. Fractional seconds are accepted by any of them.
+var timestampLayouts = []string{
+	TimestampLayout,
+	time.RFC3339,
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02T15:04:05",
+}
+
+func parseTimestamp(value string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if timestamp, err := time.Parse(layout, value); err == nil {
+			return timestamp.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// recordParser adapts a function parsing one record at a time to a Parser.
+type recordParser func(record []string) ([]Transaction, error)
+
+func (p recordParser) Parse(record []string) ([]Transaction, error) {
+	return p(record)
+}
+
+func (p recordParser) Flush() ([]Transaction, error) {
+	return nil, nil
+}
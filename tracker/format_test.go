@@ -0,0 +1,84 @@
+package tracker
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectFormat(t *testing.T) {
+	for header, want := range map[string]string{
+		"Timestamp (UTC),Transaction Description,Currency,Amount,To Currency,To Amount,Native Currency,Native Amount,Native Amount (in USD),Transaction Kind":                   "crypto.com app",
+		"Timestamp,Transaction Type,Asset,Quantity Transacted,Spot Price Currency,Spot Price at Transaction,Subtotal,Total (inclusive of fees),Fees,Notes":                      "coinbase",
+		"ID,Timestamp,Transaction Type,Asset,Quantity Transacted,Price Currency,Price at Transaction,Subtotal,Total (inclusive of fees and/or spread),Fees and/or Spread,Notes": "coinbase",
+		"txid,refid,time,type,subtype,aclass,asset,amount,fee,balance":                                                                                                          "kraken",
+		"Date(UTC),Pair,Side,Price,Executed,Amount,Fee":                                "binance",
+		"Order ID,Trade ID,Time (UTC),Instrument,Side,Price,Quantity,Fee,Fee Currency": "crypto.com exchange",
+		"\ufefftime (utc),currency,amount,fee,type,status":                             "crypto.com exchange",
+	} {
+		format, ok := DetectFormat(strings.Split(header, ","))
+		if !ok {
+			t.Errorf("%s: not detected, want %s", header, want)
+			continue
+		}
+		if format.Name() != want {
+			t.Errorf("%s: detected %s, want %s", header, format.Name(), want)
+		}
+	}
+	if format, ok := DetectFormat([]string{"Date", "Description", "Value"}); ok {
+		t.Errorf("detected %s for an unknown header", format.Name())
+	}
+}
+
+// tallyFormat is a made up export of dated CRO amounts.
+type tallyFormat struct{}
+
+func (tallyFormat) Name() string { return "tally" }
+
+func (tallyFormat) Detect(header []string) bool {
+	return len(header) == 2 && header[0] == "Tally Day" && header[1] == "CRO"
+}
+
+func (tallyFormat) Parser(header []string) (Parser, error) {
+	return tallyParser{}, nil
+}
+
+type tallyParser struct{}
+
+func (tallyParser) Parse(record []string) ([]Transaction, error) {
+	day, err := time.Parse("2006-01-02", record[0])
+	if err != nil {
+		return nil, err
+	}
+	amount, err := strconv.ParseFloat(record[1], 64)
+	if err != nil {
+		return nil, err
+	}
+	return []Transaction{{Timestamp: day, Currency: "CRO", Amount: amount, Kind: "tally"}}, nil
+}
+
+func (tallyParser) Flush() ([]Transaction, error) { return nil, nil }
+
+func TestRegisterFormat(t *testing.T) {
+	withFormats(t, tallyFormat{})
+	if names := FormatNames(); names[0] != "tally" {
+		t.Errorf("formats = %v, want tally tried first", names)
+	}
+	transactions, err := ParseTransactions(strings.NewReader("Tally Day,CRO\n2021-03-01,10\n2021-03-02,-4\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cro float64
+	for _, transaction := range transactions {
+		cro += transaction.CRODelta()
+	}
+	if len(transactions) != 2 || cro != 6 {
+		t.Errorf("parsed %+v, want two tallies adding up to 6 CRO", transactions)
+	}
+
+	_, err = ParseTransactions(strings.NewReader("Tally Day,CRO\nyesterday,10\n"))
+	if perr, ok := err.(*ParseError); !ok || perr.Line != 2 {
+		t.Errorf("err = %v, want a parse error on line 2", err)
+	}
+}
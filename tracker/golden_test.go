@@ -0,0 +1,124 @@
+package tracker_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/igaskin/crypto-tracker/tracker/sheetstest"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenPrice is the CRO price every export in testdata is valued at, on
+// goldenDate.
+const goldenPrice = 0.25
+
+var goldenDate = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// TestExportsGolden parses every export in testdata/exports, imports it into
+// a fake sheet and compares the parsed transactions, the sheet and the ROI
+// summary with the matching .golden file. Run with -update after an
+// intentional change.
+func TestExportsGolden(t *testing.T) {
+	exports, err := filepath.Glob(filepath.Join("testdata", "exports", "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) == 0 {
+		t.Fatal("no exports in testdata/exports")
+	}
+	for _, export := range exports {
+		export := export
+		t.Run(strings.TrimSuffix(filepath.Base(export), ".csv"), func(t *testing.T) {
+			got := renderExport(t, export)
+			golden := strings.TrimSuffix(export, ".csv") + ".golden"
+			if *update {
+				if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s doesn't match %s\ngot:\n%s\nwant:\n%s", export, golden, got, want)
+			}
+		})
+	}
+}
+
+func renderExport(t *testing.T, export string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	data, err := ioutil.ReadFile(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transactions, err := tracker.ParseTransactions(bytes.NewReader(data))
+	if err != nil {
+		fmt.Fprintf(&out, "error: %v\n", err)
+		return out.Bytes()
+	}
+	fmt.Fprintf(&out, "# transactions\n")
+	for _, tx := range transactions {
+		fmt.Fprintf(&out, "%s\t%s\t%s\t%s\t%s\t%g USD\t%s\tcro %+g\n",
+			tx.Timestamp.Format(tracker.TimestampLayout), tx.Description,
+			amount(tx.Currency, tx.Amount), amount(tx.ToCurrency, tx.ToAmount),
+			amount(tx.NativeCurrency, tx.NativeAmount), tx.NativeAmountUSD,
+			tx.Kind, tx.CRODelta())
+	}
+
+	fiat := "USD"
+	if len(transactions) > 0 && transactions[0].NativeCurrency != "" {
+		fiat = transactions[0].NativeCurrency
+	}
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("golden", "ROI")
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{
+		SpreadsheetID: "golden",
+		Fiat:          fiat,
+	})
+	if err := importer.Import(context.Background(), bytes.NewReader(data), fixedPrice(goldenPrice)); err != nil {
+		t.Fatalf("parsed but failed to import: %v", err)
+	}
+	fmt.Fprintf(&out, "\n# sheet\n")
+	for _, row := range server.Grid("golden", "ROI") {
+		fmt.Fprintln(&out, strings.Join(row, "\t"))
+	}
+	// fetched at changes on every run
+	fmt.Fprintf(&out, "\n# prices\n")
+	for _, row := range server.Grid("golden", tracker.DefaultPricesSheet) {
+		fmt.Fprintln(&out, strings.Join(row[:3], "\t"))
+	}
+
+	roi := tracker.ComputeROI(tracker.Purchases(transactions, fiat), goldenPrice)
+	fmt.Fprintf(&out, "\n# roi at %g %s\n", goldenPrice, fiat)
+	fmt.Fprintf(&out, "invested %.2f\ncro %.8f\naverage price %.8f\nvalue %.2f\ngain %.2f\nreturn %.4f\n",
+		roi.Invested, roi.CRO, roi.AveragePrice, roi.Value, roi.Gain, roi.Return)
+	returns, err := tracker.ComputeReturns(tracker.Purchases(transactions, fiat), goldenPrice, goldenDate)
+	if err != nil {
+		fmt.Fprintf(&out, "returns: %v\n", err)
+	} else {
+		fmt.Fprintf(&out, "xirr %.4f\ntwr %.4f\n", returns.XIRR, returns.TWR)
+	}
+	return out.Bytes()
+}
+
+func amount(currency string, amount float64) string {
+	if currency == "" && amount == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%s %g", currency, amount)
+}
@@ -0,0 +1,212 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// DefaultHistorySheet is the suggested tab for the daily history of the
+// portfolio. Backfilling it fetches a price per day, so it is opt-in.
+const DefaultHistorySheet = "History"
+
+// DateLayout is the format of the dates in the history tab.
+const DateLayout = "2006-01-02"
+
+// columns of the history tab
+const (
+	dateColumn int64 = iota
+	heldColumn
+	investedColumn
+	historyPriceColumn
+	valueColumn
+	historyWidth
+)
+
+// HistoryPoint is the portfolio at the end of a day.
+type HistoryPoint struct {
+	Date time.Time
+	// CRO held by the app wallet
+	CRO float64
+	// Invested is the fiat spent on CRO so far
+	Invested float64
+	Price    float64
+	Value    float64
+}
+
+// DailyHistory replays the transactions day by day, from the day of the
+// first one until the day of until, valuing the CRO held at the end of each
+// day at that day's price. Days missing from prices, keyed by date in
+// DateLayout, are valued at the last known price. Only purchases made in fiat
+// count as invested.
+func DailyHistory(transactions []Transaction, fiat string, prices map[string]float64, until time.Time) []HistoryPoint {
+	if len(transactions) == 0 {
+		return nil
+	}
+	sorted := append([]Transaction(nil), transactions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	day := 24 * time.Hour
+	var history []HistoryPoint
+	var point HistoryPoint
+	next := 0
+	for date := sorted[0].Timestamp.UTC().Truncate(day); !date.After(until.UTC()); date = date.Add(day) {
+		for ; next < len(sorted) && sorted[next].Timestamp.Before(date.Add(day)); next++ {
+			point.CRO += sorted[next].CRODelta()
+			if p, ok := NewPurchase(sorted[next], fiat); ok {
+				point.Invested += p.Fiat
+			}
+		}
+		if price, ok := prices[date.Format(DateLayout)]; ok {
+			point.Price = price
+		}
+		point.Date = date
+		point.Value = point.CRO * point.Price
+		history = append(history, point)
+	}
+	return history
+}
+
+// PublishHistory backfills the daily history of the transactions up to now
+// into the history tab, replacing what was there. A new tab also gets a
+// chart of invested capital against market value.
+func (t *TransactionImporter) PublishHistory(ctx context.Context, sheetName string, transactions []Transaction, prices HistoricalPriceProvider) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+	now := time.Now()
+	from := transactions[0].Timestamp
+	for _, transaction := range transactions {
+		if transaction.Timestamp.Before(from) {
+			from = transaction.Timestamp
+		}
+	}
+	daily, err := prices.DailyPrices(ctx, "CRO", t.fiat, from, now)
+	if err != nil {
+		return fmt.Errorf("failed to get CRO price history; %w", err)
+	}
+	history := DailyHistory(transactions, t.fiat, daily, now)
+
+	sheetID, added, err := t.ensureSheet(ctx, sheetName)
+	if err != nil {
+		return err
+	}
+	rows := [][]interface{}{
+		{"Date", "CRO", fmt.Sprintf("%s Invested", t.fiat), "CRO Price", fmt.Sprintf("%s Value", t.fiat)},
+	}
+	for _, point := range history {
+		rows = append(rows, []interface{}{point.Date.Format(DateLayout), point.CRO, point.Invested, point.Price, point.Value})
+	}
+	// a shorter history mustn't leave old days behind
+	rangez := SheetRange(sheetName, fmt.Sprintf("A1:%s", ColumnName(historyWidth-1)))
+	if _, err := t.Googlesheet.Spreadsheets.Values.Clear(t.SpreadsheetID, rangez, &sheets.ClearValuesRequest{}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to clear %s; %w", rangez, err)
+	}
+	_, err = t.Googlesheet.Spreadsheets.Values.Update(t.SpreadsheetID, SheetRange(sheetName, "A1"), &sheets.ValueRange{Values: rows}).ValueInputOption("USER_ENTERED").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to write history to %q; %w", sheetName, err)
+	}
+	fmt.Fprintf(t.Log, "wrote %d days of history to %q\n", len(history), sheetName)
+	if !added {
+		return nil
+	}
+
+	_, err = t.Googlesheet.Spreadsheets.BatchUpdate(t.SpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: historyFormatRequests(sheetID),
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to add the history chart; %w", err)
+	}
+	return nil
+}
+
+// historyColumn is an open ended column of the history tab, so the chart
+// and formats cover days added later.
+func historyColumn(sheetID, column int64) *sheets.GridRange {
+	return &sheets.GridRange{
+		SheetId:          sheetID,
+		StartColumnIndex: column,
+		EndColumnIndex:   column + 1,
+	}
+}
+
+func historyFormatRequests(sheetID int64) []*sheets.Request {
+	currency := func(column int64) *sheets.Request {
+		r := historyColumn(sheetID, column)
+		r.StartRowIndex = 1
+		return &sheets.Request{
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: r,
+				Cell: &sheets.CellData{
+					UserEnteredFormat: &sheets.CellFormat{
+						NumberFormat: &sheets.NumberFormat{
+							Type: "CURRENCY",
+						},
+					},
+				},
+				Fields: "userEnteredFormat.numberFormat",
+			},
+		}
+	}
+	series := func(column int64) *sheets.BasicChartSeries {
+		return &sheets.BasicChartSeries{
+			Series: &sheets.ChartData{
+				SourceRange: &sheets.ChartSourceRange{
+					Sources: []*sheets.GridRange{historyColumn(sheetID, column)},
+				},
+			},
+			TargetAxis: "LEFT_AXIS",
+		}
+	}
+	return []*sheets.Request{
+		currency(investedColumn),
+		currency(historyPriceColumn),
+		currency(valueColumn),
+		{
+			// plot invested against value, next to the data
+			AddChart: &sheets.AddChartRequest{
+				Chart: &sheets.EmbeddedChart{
+					Spec: &sheets.ChartSpec{
+						Title: "Invested vs Value",
+						BasicChart: &sheets.BasicChartSpec{
+							ChartType:      "LINE",
+							LegendPosition: "BOTTOM_LEGEND",
+							HeaderCount:    1,
+							Axis: []*sheets.BasicChartAxis{
+								{Position: "BOTTOM_AXIS", Title: "Date"},
+								{Position: "LEFT_AXIS", Title: "Value"},
+							},
+							Domains: []*sheets.BasicChartDomain{
+								{
+									Domain: &sheets.ChartData{
+										SourceRange: &sheets.ChartSourceRange{
+											Sources: []*sheets.GridRange{historyColumn(sheetID, dateColumn)},
+										},
+									},
+								},
+							},
+							Series: []*sheets.BasicChartSeries{
+								series(investedColumn),
+								series(valueColumn),
+							},
+						},
+					},
+					Position: &sheets.EmbeddedObjectPosition{
+						OverlayPosition: &sheets.OverlayPosition{
+							AnchorCell: &sheets.GridCoordinate{
+								SheetId:     sheetID,
+								RowIndex:    1,
+								ColumnIndex: historyWidth + 1,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
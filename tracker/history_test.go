@@ -0,0 +1,125 @@
+package tracker_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/igaskin/crypto-tracker/tracker/sheetstest"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestDailyHistory(t *testing.T) {
+	transactions, err := tracker.ParseTransactions(strings.NewReader(transactionsCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prices := map[string]float64{
+		"2021-03-01": 0.2,
+		"2021-03-02": 0.25,
+		// no price on the 3rd
+		"2021-03-04": 0.3,
+	}
+	history := tracker.DailyHistory(transactions, "USD", prices, time.Date(2021, 3, 5, 12, 0, 0, 0, time.UTC))
+
+	day := func(d int) time.Time {
+		return time.Date(2021, 3, d, 0, 0, 0, 0, time.UTC)
+	}
+	want := []tracker.HistoryPoint{
+		{Date: day(1), CRO: 300, Invested: 50, Price: 0.2, Value: 60},
+		{Date: day(2), CRO: 302.5, Invested: 50, Price: 0.25, Value: 75.625},
+		{Date: day(3), CRO: 402.5, Invested: 70, Price: 0.25, Value: 100.625},
+		{Date: day(4), CRO: 202.5, Invested: 70, Price: 0.3, Value: 60.75},
+		{Date: day(5), CRO: 202.5, Invested: 70, Price: 0.3, Value: 60.75},
+	}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("history = %+v\nwant %+v", history, want)
+	}
+}
+
+type dailyPrices map[string]float64
+
+func (p dailyPrices) DailyPrices(ctx context.Context, asset, fiat string, from, to time.Time) (map[string]float64, error) {
+	return p, nil
+}
+
+func TestPublishHistory(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI")
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet"})
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	recent := func(days int, cro float64) tracker.Transaction {
+		return tracker.Transaction{
+			Timestamp:      now.AddDate(0, 0, -days),
+			Description:    string(tracker.BuyCRO),
+			Currency:       "CRO",
+			Amount:         cro,
+			NativeCurrency: "USD",
+			NativeAmount:   cro / 10,
+		}
+	}
+	prices := dailyPrices{}
+	for days := 0; days <= 3; days++ {
+		prices[now.AddDate(0, 0, -days).Format(tracker.DateLayout)] = 0.5
+	}
+
+	history := []tracker.Transaction{recent(3, 100), recent(1, 100)}
+	if err := importer.PublishHistory(ctx, tracker.DefaultHistorySheet, history, prices); err != nil {
+		t.Fatal(err)
+	}
+	grid := server.Grid("sheet", tracker.DefaultHistorySheet)
+	if len(grid) != 5 {
+		t.Fatalf("history has %d rows, want a header and 4 days:\n%q", len(grid), grid)
+	}
+	if got, want := grid[4], []string{now.Format(tracker.DateLayout), "200", "20", "0.5", "100"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("today = %q, want %q", got, want)
+	}
+
+	sheet := findSheet(t, server, tracker.DefaultHistorySheet)
+	if len(sheet.Charts) != 1 {
+		t.Fatalf("history tab has %d charts, want 1", len(sheet.Charts))
+	}
+	chart := sheet.Charts[0].Spec.BasicChart
+	if chart.ChartType != "LINE" || len(chart.Series) != 2 {
+		t.Errorf("chart = %+v, want two lines", chart)
+	}
+	for i, column := range []int64{2, 4} {
+		if got := chart.Series[i].Series.SourceRange.Sources[0].StartColumnIndex; got != column {
+			t.Errorf("series %d plots column %d, want %d", i, got, column)
+		}
+	}
+
+	// republishing a shorter history clears the old days and keeps the one chart
+	if err := importer.PublishHistory(ctx, tracker.DefaultHistorySheet, history[1:], prices); err != nil {
+		t.Fatal(err)
+	}
+	var rows int
+	for _, row := range server.Grid("sheet", tracker.DefaultHistorySheet) {
+		if row[0] != "" {
+			rows++
+		}
+	}
+	if rows != 3 {
+		t.Errorf("history has %d rows, want a header and 2 days", rows)
+	}
+	if got := len(findSheet(t, server, tracker.DefaultHistorySheet).Charts); got != 1 {
+		t.Errorf("history tab has %d charts, want 1", got)
+	}
+}
+
+func findSheet(t *testing.T, server *sheetstest.Server, title string) *sheets.Sheet {
+	t.Helper()
+	for _, sheet := range server.Spreadsheet("sheet").Sheets {
+		if sheet.Properties.Title == title {
+			return sheet
+		}
+	}
+	t.Fatalf("no %q tab", title)
+	return nil
+}
@@ -0,0 +1,172 @@
+package tracker
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kraken ledgers list every change to a balance as a row of its own, with the
+// fee charged on it. The two legs of a trade share a reference id and are
+// joined into one transaction.
+
+// krakenColumns are the normalized header names of the fields of a Kraken
+// ledger export.
+var krakenColumns = map[string][]string{
+	"txid":   {"txid"},
+	"refid":  {"refid"},
+	"time":   {"time"},
+	"type":   {"type"},
+	"asset":  {"asset"},
+	"amount": {"amount"},
+	"fee":    {"fee"},
+}
+
+var krakenFields = []string{"txid", "refid", "time", "type", "asset", "amount", "fee"}
+
+// krakenAssets maps Kraken's legacy asset codes to their usual symbols.
+// Other four letter codes starting with X or Z drop the prefix.
+var krakenAssets = map[string]string{
+	"XBT":  "BTC",
+	"XXBT": "BTC",
+	"XDG":  "DOGE",
+	"XXDG": "DOGE",
+}
+
+// krakenMovements maps the ledger types that aren't trades to their kind
+// and description. Other types are recorded as adjustments.
+var krakenMovements = map[string][2]string{
+	"deposit":    {ExchangeDepositKind, ExchangeDeposit},
+	"withdrawal": {ExchangeWithdrawalKind, ExchangeWithdrawal},
+	"transfer":   {ExchangeTransferKind, ExchangeTransfer},
+	"staking":    {ExchangeStakingRewardKind, ExchangeStakingReward},
+	"earn":       {ExchangeStakingRewardKind, ExchangeStakingReward},
+}
+
+// krakenAsset normalizes a Kraken asset code, dropping the suffix of staked
+// balances such as DOT.S.
+func krakenAsset(code string) string {
+	code = strings.ToUpper(code)
+	if i := strings.Index(code, "."); i > 0 {
+		code = code[:i]
+	}
+	if asset, ok := krakenAssets[code]; ok {
+		return asset
+	}
+	if len(code) == 4 && (code[0] == 'X' || code[0] == 'Z') {
+		return code[1:]
+	}
+	return code
+}
+
+// krakenFormat is a Kraken ledger export.
+type krakenFormat struct{}
+
+func (krakenFormat) Name() string {
+	return "kraken"
+}
+
+func (krakenFormat) Detect(header []string) bool {
+	return locateColumns(header, krakenColumns).has(krakenFields...)
+}
+
+func (krakenFormat) Parser(header []string) (Parser, error) {
+	return &krakenParser{columns: locateColumns(header, krakenColumns), legs: map[string]*krakenEntry{}}, nil
+}
+
+// krakenEntry is a row of a ledger.
+type krakenEntry struct {
+	timestamp time.Time
+	asset     string
+	amount    float64
+	fee       float64
+}
+
+// krakenParser holds on to the first leg of every trade until the second
+// one turns up.
+type krakenParser struct {
+	columns columns
+	legs    map[string]*krakenEntry
+}
+
+func (p *krakenParser) Parse(record []string) ([]Transaction, error) {
+	c := p.columns
+	var text [4]string
+	var err error
+	for i, field := range []string{"txid", "refid", "type", "asset"} {
+		if text[i], err = c.get(record, field); err != nil {
+			return nil, err
+		}
+	}
+	txid, refid, kind := text[0], text[1], strings.ToLower(text[2])
+	// the ledger repeats unconfirmed deposits and withdrawals without a txid
+	if txid == "" {
+		return nil, nil
+	}
+	entry := &krakenEntry{asset: krakenAsset(text[3])}
+	if entry.timestamp, err = c.timestamp(record, "time"); err != nil {
+		return nil, err
+	}
+	if entry.amount, err = c.number(record, "amount"); err != nil {
+		return nil, err
+	}
+	if entry.fee, err = c.number(record, "fee"); err != nil {
+		return nil, err
+	}
+
+	if kind == "trade" || kind == "spend" || kind == "receive" {
+		first, ok := p.legs[refid]
+		if !ok {
+			p.legs[refid] = entry
+			return nil, nil
+		}
+		delete(p.legs, refid)
+		return krakenTrade(first, entry)
+	}
+
+	movement, ok := krakenMovements[kind]
+	if !ok {
+		movement = [2]string{ExchangeAdjustmentKind, ExchangeAdjustment}
+	}
+	transactions := []Transaction{newMovement(entry.timestamp, movement[1], movement[0], entry.asset, entry.amount)}
+	if entry.fee != 0 {
+		transactions = append(transactions, newFee(entry.timestamp, entry.asset, entry.fee))
+	}
+	return transactions, nil
+}
+
+// Flush fails when a trade is missing a leg, which happens when the export
+// ends in the middle of one.
+func (p *krakenParser) Flush() ([]Transaction, error) {
+	if len(p.legs) == 0 {
+		return nil, nil
+	}
+	var refids []string
+	for refid := range p.legs {
+		refids = append(refids, refid)
+	}
+	sort.Strings(refids)
+	return nil, fmt.Errorf("trade %s has only one leg", strings.Join(refids, ", "))
+}
+
+// krakenTrade joins the legs of a trade, whichever order they came in.
+func krakenTrade(a, b *krakenEntry) ([]Transaction, error) {
+	spent, bought := a, b
+	if spent.amount > 0 {
+		spent, bought = b, a
+	}
+	if spent.amount > 0 || bought.amount < 0 {
+		return nil, fmt.Errorf("trade legs %g %s and %g %s don't spend one asset on another", a.amount, a.asset, b.amount, b.asset)
+	}
+	trade := newTrade(bought.timestamp, tradeDescription(spent.asset, bought.asset),
+		spent.asset, math.Abs(spent.amount), bought.asset, bought.amount)
+	transactions := []Transaction{trade}
+	for _, leg := range []*krakenEntry{spent, bought} {
+		if leg.fee != 0 {
+			transactions = append(transactions, newFee(leg.timestamp, leg.asset, leg.fee))
+		}
+	}
+	return transactions, nil
+}
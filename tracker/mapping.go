@@ -0,0 +1,277 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mapping describes a csv export without a built in format column by
+// column, so any export with a header row can be imported. Mappings are
+// usually read from the formats list of the config file.
+type Mapping struct {
+	// Name identifies the format in messages
+	Name    string         `mapstructure:"name"`
+	Columns MappingColumns `mapstructure:"columns"`
+	// TimestampLayout is the Go time layout of the timestamp column, by
+	// default any of the layouts the built in formats use
+	TimestampLayout string `mapstructure:"timestamp-layout"`
+	// Fiat is the currency of the fiat amount column
+	Fiat string `mapstructure:"fiat"`
+	// Kinds are tried in order against the kind column, the first rule
+	// matching a row deciding what kind of transaction it is
+	Kinds []KindRule `mapstructure:"kinds"`
+}
+
+// MappingColumns locates the fields of a mapped export. A column is given by
+// its header name or by its number, counting from 1. Timestamp, asset and
+// amount are required.
+type MappingColumns struct {
+	Timestamp  string `mapstructure:"timestamp"`
+	Kind       string `mapstructure:"kind"`
+	Asset      string `mapstructure:"asset"`
+	Amount     string `mapstructure:"amount"`
+	FiatAmount string `mapstructure:"fiat-amount"`
+}
+
+// KindRule maps the rows whose kind matches a regular expression to one of
+// the MappedKinds.
+type KindRule struct {
+	Match string `mapstructure:"match"`
+	Kind  string `mapstructure:"kind"`
+}
+
+// mappedKind is what a mapped row turns into: trades spend or buy the asset
+// for fiat, movements add to or, when negative, take from the balance.
+type mappedKind struct {
+	description string
+	kind        string
+	// sign forces the sign of a movement's amount, 0 keeps the export's
+	sign float64
+}
+
+// the kinds rules may map rows to, besides skip
+var mappedKinds = map[string]mappedKind{
+	"buy":        {string(ExchangeBuy), ExchangeTradeKind, 0},
+	"sell":       {ExchangeSell, ExchangeTradeKind, 0},
+	"deposit":    {ExchangeDeposit, ExchangeDepositKind, 1},
+	"withdrawal": {ExchangeWithdrawal, ExchangeWithdrawalKind, -1},
+	"fee":        {ExchangeFee, ExchangeFeeKind, -1},
+	"staking":    {ExchangeStakingReward, ExchangeStakingRewardKind, 1},
+	"interest":   {ExchangeInterest, ExchangeInterestKind, 1},
+	"reward":     {ExchangeReward, ExchangeRewardKind, 1},
+	"transfer":   {ExchangeTransfer, ExchangeTransferKind, 0},
+	"adjustment": {ExchangeAdjustment, ExchangeAdjustmentKind, 0},
+}
+
+// skipKind leaves matching rows out of the import.
+const skipKind = "skip"
+
+// MappedKinds lists the kinds rules may map rows to.
+func MappedKinds() []string {
+	return []string{"buy", "sell", "deposit", "withdrawal", "fee", "staking", "interest", "reward", "transfer", "adjustment", skipKind}
+}
+
+// mappingFormat is an export described by a Mapping.
+type mappingFormat struct {
+	Mapping
+	rules []kindRule
+}
+
+type kindRule struct {
+	match *regexp.Regexp
+	kind  string
+}
+
+// NewMappingFormat checks a mapping and returns the format it describes.
+func NewMappingFormat(m Mapping) (Format, error) {
+	if m.Name == "" {
+		return nil, errors.New("missing name")
+	}
+	f := &mappingFormat{Mapping: m}
+	named := false
+	for field, column := range f.columns() {
+		if column == "" {
+			if field == "timestamp" || field == "asset" || field == "amount" {
+				return nil, fmt.Errorf("%s: missing %s column", m.Name, field)
+			}
+			continue
+		}
+		if _, ok := columnNumber(column); !ok {
+			named = true
+		}
+	}
+	// a header row is only recognized by the names in it
+	if !named {
+		return nil, fmt.Errorf("%s: at least one column must be given by its header name", m.Name)
+	}
+	if len(m.Kinds) > 0 && m.Columns.Kind == "" {
+		return nil, fmt.Errorf("%s: kind rules need a kind column", m.Name)
+	}
+	for _, rule := range m.Kinds {
+		kind := strings.ToLower(rule.Kind)
+		if _, ok := mappedKinds[kind]; !ok && kind != skipKind {
+			return nil, fmt.Errorf("%s: unknown kind %q, expected one of %s", m.Name, rule.Kind, strings.Join(MappedKinds(), ", "))
+		}
+		if (kind == "buy" || kind == "sell") && (m.Fiat == "" || m.Columns.FiatAmount == "") {
+			return nil, fmt.Errorf("%s: %s rules need fiat and a fiat amount column", m.Name, kind)
+		}
+		match, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid match for %s rule; %w", m.Name, kind, err)
+		}
+		f.rules = append(f.rules, kindRule{match, kind})
+	}
+	f.Fiat = strings.ToUpper(f.Fiat)
+	return f, nil
+}
+
+func (f *mappingFormat) columns() map[string]string {
+	return map[string]string{
+		"timestamp":   f.Columns.Timestamp,
+		"kind":        f.Columns.Kind,
+		"asset":       f.Columns.Asset,
+		"amount":      f.Columns.Amount,
+		"fiat amount": f.Columns.FiatAmount,
+	}
+}
+
+// columnNumber reads a column given by number as an index into a record.
+func columnNumber(column string) (int, bool) {
+	n, err := strconv.Atoi(column)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n - 1, true
+}
+
+func (f *mappingFormat) Name() string {
+	return f.Mapping.Name
+}
+
+// locate finds every column of the mapping in a header row, preferring a
+// header name over a column number.
+func (f *mappingFormat) locate(header []string) (columns, bool) {
+	c := columns{}
+	for field, column := range f.columns() {
+		if column == "" {
+			continue
+		}
+		want := normalizeColumnName(column)
+		for i, name := range header {
+			if normalizeColumnName(name) == want {
+				c[field] = i
+				break
+			}
+		}
+		if _, ok := c[field]; ok {
+			continue
+		}
+		i, ok := columnNumber(column)
+		if !ok || i >= len(header) {
+			return nil, false
+		}
+		c[field] = i
+	}
+	return c, true
+}
+
+func (f *mappingFormat) Detect(header []string) bool {
+	_, ok := f.locate(header)
+	return ok
+}
+
+func (f *mappingFormat) Parser(header []string) (Parser, error) {
+	c, ok := f.locate(header)
+	if !ok {
+		return nil, fmt.Errorf("header doesn't have the columns of %s", f.Mapping.Name)
+	}
+	return recordParser(func(record []string) ([]Transaction, error) {
+		return f.transaction(c, record)
+	}), nil
+}
+
+func (f *mappingFormat) transaction(c columns, record []string) ([]Transaction, error) {
+	kind, err := c.get(record, "kind")
+	if err != nil {
+		return nil, err
+	}
+	rule := "adjustment"
+	if len(f.rules) > 0 {
+		rule = ""
+		for _, r := range f.rules {
+			if r.match.MatchString(kind) {
+				rule = r.kind
+				break
+			}
+		}
+		if rule == "" {
+			return nil, fmt.Errorf("no kind rule matches %q", kind)
+		}
+	}
+	if rule == skipKind {
+		return nil, nil
+	}
+
+	timestamp, err := f.timestamp(c, record)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := c.get(record, "asset")
+	if err != nil {
+		return nil, err
+	}
+	if asset == "" {
+		return nil, errors.New("missing asset")
+	}
+	asset = strings.ToUpper(asset)
+	amount, err := c.number(record, "amount")
+	if err != nil {
+		return nil, err
+	}
+	fiatAmount, err := c.number(record, "fiat amount")
+	if err != nil {
+		return nil, err
+	}
+	fiatAmount = math.Abs(fiatAmount)
+
+	mapped := mappedKinds[rule]
+	var t Transaction
+	switch rule {
+	case "buy":
+		t = newTrade(timestamp, mapped.description, f.Fiat, fiatAmount, asset, math.Abs(amount))
+	case "sell":
+		t = newTrade(timestamp, mapped.description, asset, math.Abs(amount), f.Fiat, fiatAmount)
+	default:
+		if mapped.sign != 0 {
+			amount = mapped.sign * math.Abs(amount)
+		}
+		t = newMovement(timestamp, mapped.description, mapped.kind, asset, amount)
+	}
+	// rows without a fiat amount keep whatever value the asset implies
+	if f.Fiat != "" && fiatAmount != 0 {
+		t.setNative(f.Fiat, fiatAmount)
+	}
+	return []Transaction{t}, nil
+}
+
+// timestamp parses the timestamp column in the mapping's layout, if it has
+// one.
+func (f *mappingFormat) timestamp(c columns, record []string) (time.Time, error) {
+	if f.TimestampLayout == "" {
+		return c.timestamp(record, "timestamp")
+	}
+	value, err := c.get(record, "timestamp")
+	if err != nil {
+		return time.Time{}, err
+	}
+	timestamp, err := time.Parse(f.TimestampLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected the layout %s", value, f.TimestampLayout)
+	}
+	return timestamp.UTC(), nil
+}
@@ -0,0 +1,126 @@
+package tracker
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// coolexMapping maps a made up exchange export naming some of its columns
+// and leaving the asset to the third column.
+var coolexMapping = Mapping{
+	Name: "coolex",
+	Columns: MappingColumns{
+		Timestamp:  "Date",
+		Kind:       "Operation",
+		Asset:      "3",
+		Amount:     "Qty",
+		FiatAmount: "Value (EUR)",
+	},
+	TimestampLayout: "02/01/2006 15:04",
+	Fiat:            "eur",
+	Kinds: []KindRule{
+		{Match: `(?i)^market buy`, Kind: "buy"},
+		{Match: `(?i)^market sell`, Kind: "sell"},
+		{Match: `(?i)commission`, Kind: "fee"},
+		{Match: `(?i)^staking`, Kind: "staking"},
+		{Match: `(?i)^(payout|withdraw)`, Kind: "withdrawal"},
+		{Match: `(?i)^note`, Kind: "skip"},
+	},
+}
+
+const coolexExport = `Date,Operation,Coin,Qty,Value (EUR)
+01/03/2021 09:30,Market Buy,cro,"1,000",€100
+01/03/2021 09:30,Trade commission,CRO,1,0.10
+08/03/2021 00:00,Staking reward,CRO,2.5,
+09/03/2021 12:00,Note,,,
+10/03/2021 18:45,Payout,CRO,500,60
+`
+
+func withFormats(t *testing.T, registered ...Format) {
+	t.Helper()
+	builtin := formats
+	t.Cleanup(func() { formats = builtin })
+	for _, f := range registered {
+		RegisterFormat(f)
+	}
+}
+
+func TestMappingFormat(t *testing.T) {
+	format, err := NewMappingFormat(coolexMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFormats(t, format)
+	transactions, err := ParseTransactions(strings.NewReader(coolexExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2021, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+	want := []Transaction{
+		{Timestamp: at(1, 9, 30), Description: string(ExchangeBuy), Currency: "EUR", Amount: -100, ToCurrency: "CRO", ToAmount: 1000, NativeCurrency: "EUR", NativeAmount: 100, Kind: ExchangeTradeKind},
+		{Timestamp: at(1, 9, 30), Description: ExchangeFee, Currency: "CRO", Amount: -1, NativeCurrency: "EUR", NativeAmount: 0.1, Kind: ExchangeFeeKind},
+		{Timestamp: at(8, 0, 0), Description: ExchangeStakingReward, Currency: "CRO", Amount: 2.5, Kind: ExchangeStakingRewardKind},
+		{Timestamp: at(10, 18, 45), Description: ExchangeWithdrawal, Currency: "CRO", Amount: -500, NativeCurrency: "EUR", NativeAmount: 60, Kind: ExchangeWithdrawalKind},
+	}
+	if len(transactions) != len(want) {
+		t.Fatalf("parsed %d transactions, want %d: %+v", len(transactions), len(want), transactions)
+	}
+	for i := range want {
+		if transactions[i] != want[i] {
+			t.Errorf("transaction %d = %+v, want %+v", i, transactions[i], want[i])
+		}
+	}
+	if p, ok := NewPurchase(transactions[0], "EUR"); !ok || p.Fiat != 100 || p.CRO != 1000 {
+		t.Errorf("purchase = %+v, %v, want 100 EUR for 1000 CRO", p, ok)
+	}
+
+	_, err = ParseTransactions(strings.NewReader("Date,Operation,Coin,Qty,Value (EUR)\n01/03/2021 09:30,Airdrop,CRO,5,\n"))
+	if perr, ok := err.(*ParseError); !ok || perr.Line != 2 || !strings.Contains(err.Error(), `no kind rule matches "Airdrop"`) {
+		t.Errorf("err = %v, want no kind rule matching on line 2", err)
+	}
+}
+
+func TestMappingWithoutKindRules(t *testing.T) {
+	format, err := NewMappingFormat(Mapping{
+		Name:    "ledger",
+		Columns: MappingColumns{Timestamp: "When", Asset: "Asset", Amount: "Change"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.Detect([]string{"When", "Asset"}) {
+		t.Error("detected a header missing the amount column")
+	}
+	withFormats(t, format)
+	transactions, err := ParseTransactions(strings.NewReader("When,Asset,Change\n2021-03-01T10:00:00Z,cro,-3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 1 || transactions[0].Kind != ExchangeAdjustmentKind || transactions[0].CRODelta() != -3 {
+		t.Errorf("parsed %+v, want an adjustment of -3 CRO", transactions)
+	}
+}
+
+func TestNewMappingFormatErrors(t *testing.T) {
+	columns := MappingColumns{Timestamp: "Date", Kind: "Type", Asset: "Coin", Amount: "Qty"}
+	for _, test := range []struct {
+		mapping Mapping
+		want    string
+	}{
+		{Mapping{Columns: columns}, "missing name"},
+		{Mapping{Name: "x", Columns: MappingColumns{Timestamp: "Date", Amount: "Qty"}}, "missing asset column"},
+		{Mapping{Name: "x", Columns: MappingColumns{Timestamp: "1", Asset: "2", Amount: "3"}}, "header name"},
+		{Mapping{Name: "x", Columns: MappingColumns{Timestamp: "Date", Asset: "Coin", Amount: "Qty"}, Kinds: []KindRule{{".*", "deposit"}}}, "need a kind column"},
+		{Mapping{Name: "x", Columns: columns, Kinds: []KindRule{{".*", "airdrop"}}}, `unknown kind "airdrop"`},
+		{Mapping{Name: "x", Columns: columns, Kinds: []KindRule{{"buy", "buy"}}}, "need fiat and a fiat amount column"},
+		{Mapping{Name: "x", Columns: columns, Kinds: []KindRule{{"(", "fee"}}}, "invalid match for fee rule"},
+	} {
+		_, err := NewMappingFormat(test.mapping)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%+v: err = %v, want %q", test.mapping, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package tracker
+
+import "sort"
+
+// Holding is the amount of an asset held by the app wallet.
+type Holding struct {
+	Asset  string
+	Amount float64
+}
+
+// Holdings totals the crypto held by the app wallet after the transactions,
+// ordered by asset. Fiat is left out; the fiat wallet has its own export.
+func Holdings(transactions []Transaction) []Holding {
+	amounts := map[string]float64{}
+	add := func(asset, native string, amount float64) {
+		if asset != "" && asset != native {
+			amounts[asset] += amount
+		}
+	}
+	for _, t := range transactions {
+		add(t.Currency, t.NativeCurrency, t.Amount)
+		add(t.ToCurrency, t.NativeCurrency, t.ToAmount)
+	}
+	var holdings []Holding
+	for asset, amount := range amounts {
+		holdings = append(holdings, Holding{Asset: asset, Amount: amount})
+	}
+	sort.Slice(holdings, func(i, j int) bool { return holdings[i].Asset < holdings[j].Asset })
+	return holdings
+}
+
+// sources of CRO paid out without being bought
+const (
+	EarnIncome     = "crypto earn"
+	StakingRewards = "staking rewards"
+	CardRewards    = "card rewards"
+	Referrals      = "referrals"
+)
+
+// IncomeSources are the income sources in the order they're reported.
+var IncomeSources = []string{EarnIncome, StakingRewards, CardRewards, Referrals}
+
+// incomeKinds maps the transaction kinds that pay out CRO to their source.
+var incomeKinds = map[string]string{
+	"crypto_earn_interest_paid": EarnIncome,
+	"mco_stake_reward":          StakingRewards,
+	"referral_card_cashback":    CardRewards,
+	"reimbursement":             CardRewards,
+	"referral_gift":             Referrals,
+	"referral_bonus":            Referrals,
+	ExchangeInterestKind:        EarnIncome,
+	ExchangeStakingRewardKind:   StakingRewards,
+	ExchangeRewardKind:          Referrals,
+}
+
+// Income is the CRO paid out by a source.
+type Income struct {
+	Source  string
+	Payouts int
+	CRO     float64
+	// Fiat is what the payouts were worth when they were paid
+	Fiat float64
+}
+
+// IncomeBySource totals the CRO paid out by every income source, in the
+// order of IncomeSources.
+func IncomeBySource(transactions []Transaction) []Income {
+	totals := map[string]*Income{}
+	for _, t := range transactions {
+		source, ok := incomeKinds[t.Kind]
+		if !ok {
+			continue
+		}
+		total, ok := totals[source]
+		if !ok {
+			total = &Income{Source: source}
+			totals[source] = total
+		}
+		total.Payouts++
+		total.CRO += t.CRODelta()
+		total.Fiat += t.NativeAmount
+	}
+	var income []Income
+	for _, source := range IncomeSources {
+		if total, ok := totals[source]; ok {
+			income = append(income, *total)
+		}
+	}
+	return income
+}
@@ -0,0 +1,40 @@
+package tracker_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+)
+
+func TestHoldingsAndIncome(t *testing.T) {
+	transactions, err := tracker.ReadTransactionsFile("testdata/exports/usd_all_types.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	holdings := tracker.Holdings(transactions)
+	if len(holdings) != 2 || holdings[0].Asset != "BTC" || holdings[1].Asset != "CRO" {
+		t.Fatalf("holdings = %+v, want BTC and CRO", holdings)
+	}
+	if holdings[0].Amount != 0.0000875 {
+		t.Errorf("BTC = %v, want 0.0000875", holdings[0].Amount)
+	}
+	var cro float64
+	for _, t := range transactions {
+		cro += t.CRODelta()
+	}
+	if holdings[1].Amount != cro {
+		t.Errorf("CRO = %v, want the app wallet balance %v", holdings[1].Amount, cro)
+	}
+
+	want := []tracker.Income{
+		{Source: tracker.EarnIncome, Payouts: 1, CRO: 1.15, Fiat: 0.07},
+		{Source: tracker.StakingRewards, Payouts: 1, CRO: 0.52, Fiat: 0.03},
+		{Source: tracker.CardRewards, Payouts: 2, CRO: 3.2 + 16.13, Fiat: 0.2 + 1},
+		{Source: tracker.Referrals, Payouts: 2, CRO: 161.29 + 80.65, Fiat: 25 + 5},
+	}
+	if got := tracker.IncomeBySource(transactions); !reflect.DeepEqual(got, want) {
+		t.Errorf("income = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,21 @@
+package tracker
+
+import (
+	"context"
+	"time"
+)
+
+// PriceProvider looks up the current market price of an asset.
+type PriceProvider interface {
+	// Price returns the price of one unit of asset (e.g. "CRO") in fiat
+	// (e.g. "USD").
+	Price(ctx context.Context, asset, fiat string) (float64, error)
+}
+
+// HistoricalPriceProvider looks up past market prices of an asset.
+type HistoricalPriceProvider interface {
+	// DailyPrices returns the price of one unit of asset in fiat on every
+	// UTC day from from to to, keyed by date in DateLayout. Days without a
+	// price may be left out.
+	DailyPrices(ctx context.Context, asset, fiat string, from, to time.Time) (map[string]float64, error)
+}
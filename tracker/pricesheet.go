@@ -0,0 +1,123 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// DefaultPricesSheet is the tab holding the prices the table is valued at.
+const DefaultPricesSheet = "Prices"
+
+// columns of the prices tab
+const (
+	assetColumn int64 = iota
+	quoteFiatColumn
+	priceColumn
+	fetchedAtColumn
+)
+
+// Quote is the price of an asset in a fiat currency at the time it was
+// fetched.
+type Quote struct {
+	Asset     string
+	Fiat      string
+	Price     float64
+	FetchedAt time.Time
+}
+
+// quotePrice writes the current CRO price to the prices tab and returns the
+// absolute reference of its cell, which the row formulas value the table at.
+func (t *TransactionImporter) quotePrice(ctx context.Context, price float64) (string, error) {
+	var rows [][]interface{}
+	if t.priceRow == 0 {
+		row, empty, err := t.findPriceRow(ctx, "CRO")
+		if err != nil {
+			return "", err
+		}
+		if empty {
+			rows = append(rows, []interface{}{"Asset", "Fiat", "Price", "Fetched At (UTC)"})
+		}
+		t.priceRow = row
+	}
+	rows = append(rows, []interface{}{"CRO", t.fiat, price, time.Now().UTC().Format(TimestampLayout)})
+
+	start := t.priceRow - int64(len(rows)) + 1
+	rangez := SheetRange(t.pricesSheet, Cell(assetColumn, start))
+	_, err := t.Googlesheet.Spreadsheets.Values.Update(t.SpreadsheetID, rangez, &sheets.ValueRange{Values: rows}).ValueInputOption("USER_ENTERED").Context(ctx).Do()
+	if err != nil {
+		t.priceRow = 0
+		return "", fmt.Errorf("failed to write the CRO price to %q; %w", t.pricesSheet, err)
+	}
+	return SheetRange(t.pricesSheet, fmt.Sprintf("$%s$%d", ColumnName(priceColumn), t.priceRow)), nil
+}
+
+// findPriceRow finds the row listing the price of asset in the importer's
+// fiat, adding the prices tab when the spreadsheet doesn't have it yet.
+// Pairs that aren't listed get the first row below the others; empty is set
+// when the tab has no header yet.
+func (t *TransactionImporter) findPriceRow(ctx context.Context, asset string) (row int64, empty bool, err error) {
+	if _, _, err := t.ensureSheet(ctx, t.pricesSheet); err != nil {
+		return 0, false, err
+	}
+	rangez := SheetRange(t.pricesSheet, fmt.Sprintf("A1:%s", ColumnName(quoteFiatColumn)))
+	resp, err := t.Googlesheet.Spreadsheets.Values.Get(t.SpreadsheetID, rangez).ValueRenderOption("FORMULA").Context(ctx).Do()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read %s; %w", rangez, err)
+	}
+	if len(resp.Values) == 0 {
+		return 2, true, nil
+	}
+	for i, values := range resp.Values[1:] {
+		if len(values) > int(quoteFiatColumn) && values[assetColumn] == asset && values[quoteFiatColumn] == t.fiat {
+			return int64(i) + 2, false, nil
+		}
+	}
+	return int64(len(resp.Values)) + 1, false, nil
+}
+
+// RefreshPrices fetches the current price of every asset and fiat pair
+// listed in the prices tab and writes them back in a single update, which
+// revalues every table referencing the tab.
+func (t *TransactionImporter) RefreshPrices(ctx context.Context, prices PriceProvider) ([]Quote, error) {
+	rangez := SheetRange(t.pricesSheet, fmt.Sprintf("A2:%s", ColumnName(quoteFiatColumn)))
+	resp, err := t.Googlesheet.Spreadsheets.Values.Get(t.SpreadsheetID, rangez).ValueRenderOption("FORMULA").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s; %w", rangez, err)
+	}
+
+	var quotes []Quote
+	var values [][]interface{}
+	now := time.Now()
+	for _, row := range resp.Values {
+		asset, fiat := "", ""
+		if len(row) > int(quoteFiatColumn) {
+			asset, _ = row[assetColumn].(string)
+			fiat, _ = row[quoteFiatColumn].(string)
+		}
+		if asset == "" || fiat == "" {
+			// an empty row leaves the cells as they are
+			values = append(values, []interface{}{})
+			continue
+		}
+		price, err := prices.Price(ctx, asset, fiat)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s price; %w", asset, err)
+		}
+		quote := Quote{Asset: asset, Fiat: fiat, Price: price, FetchedAt: now}
+		quotes = append(quotes, quote)
+		values = append(values, []interface{}{quote.Price, quote.FetchedAt.UTC().Format(TimestampLayout)})
+	}
+	if len(quotes) == 0 {
+		return nil, nil
+	}
+
+	rangez = SheetRange(t.pricesSheet, Cell(priceColumn, 2))
+	_, err = t.Googlesheet.Spreadsheets.Values.Update(t.SpreadsheetID, rangez, &sheets.ValueRange{Values: values}).ValueInputOption("USER_ENTERED").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write prices to %q; %w", t.pricesSheet, err)
+	}
+	return quotes, nil
+}
@@ -0,0 +1,124 @@
+package tracker
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// CashFlow is money put into the portfolio, negative, or taken out of it,
+// positive.
+type CashFlow struct {
+	Time   time.Time
+	Amount float64
+}
+
+// Returns are the returns of a set of purchases that account for when the
+// money went in.
+type Returns struct {
+	// XIRR is the annualized money weighted return
+	XIRR float64
+	// TWR is the time weighted return since the first purchase, not
+	// annualized
+	TWR float64
+}
+
+// CashFlows turns the purchases into cash flows, closed by selling
+// everything at price at the given time.
+func CashFlows(purchases []Purchase, price float64, at time.Time) []CashFlow {
+	var flows []CashFlow
+	var held float64
+	for _, p := range purchases {
+		flows = append(flows, CashFlow{Time: p.Timestamp, Amount: -p.Fiat})
+		held += p.CRO
+	}
+	return append(flows, CashFlow{Time: at, Amount: held * price})
+}
+
+// ComputeReturns values the purchases at price at the given time.
+func ComputeReturns(purchases []Purchase, price float64, at time.Time) (Returns, error) {
+	if len(purchases) == 0 {
+		return Returns{}, errors.New("no purchases")
+	}
+	xirr, err := XIRR(CashFlows(purchases, price, at))
+	if err != nil {
+		return Returns{}, err
+	}
+	return Returns{XIRR: xirr, TWR: TimeWeightedReturn(purchases, price)}, nil
+}
+
+// TimeWeightedReturn chains the returns of the periods between purchases,
+// valuing the CRO held at the price of each purchase, so the size and
+// timing of the purchases don't matter.
+func TimeWeightedReturn(purchases []Purchase, price float64) float64 {
+	sorted := append([]Purchase(nil), purchases...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+	growth := 1.0
+	var held, value float64
+	for _, p := range sorted {
+		if held > 0 && value > 0 {
+			growth *= held * p.Price() / value
+		}
+		held += p.CRO
+		value = held * p.Price()
+	}
+	if value == 0 {
+		return 0
+	}
+	return growth*held*price/value - 1
+}
+
+// XIRR finds the annual rate at which the cash flows have a net present
+// value of zero, like the XIRR spreadsheet function.
+func XIRR(flows []CashFlow) (float64, error) {
+	if len(flows) == 0 {
+		return 0, errors.New("no cash flows")
+	}
+	var in, out bool
+	start, end := flows[0].Time, flows[0].Time
+	for _, flow := range flows {
+		in = in || flow.Amount < 0
+		out = out || flow.Amount > 0
+		if flow.Time.Before(start) {
+			start = flow.Time
+		}
+		if flow.Time.After(end) {
+			end = flow.Time
+		}
+	}
+	if !in || !out {
+		return 0, errors.New("xirr needs money going in and out")
+	}
+	if !end.After(start) {
+		return 0, errors.New("xirr needs cash flows at different times")
+	}
+	npv := func(rate float64) float64 {
+		var sum float64
+		for _, flow := range flows {
+			years := flow.Time.Sub(start).Hours() / 24 / 365
+			sum += flow.Amount / math.Pow(1+rate, years)
+		}
+		return sum
+	}
+
+	// bracket the rate, then bisect
+	low, high := -0.999999999, 1.0
+	for math.Signbit(npv(low)) == math.Signbit(npv(high)) {
+		high *= 2
+		if high > 1e12 {
+			return 0, errors.New("xirr doesn't converge")
+		}
+	}
+	for i := 0; i < 200 && high-low > 1e-12; i++ {
+		mid := (low + high) / 2
+		if math.Signbit(npv(mid)) == math.Signbit(npv(low)) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2, nil
+}
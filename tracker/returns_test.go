@@ -0,0 +1,83 @@
+package tracker_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestXIRR(t *testing.T) {
+	// the example from the spreadsheet XIRR documentation
+	rate, err := tracker.XIRR([]tracker.CashFlow{
+		{Time: date(2008, 1, 1), Amount: -10000},
+		{Time: date(2008, 3, 1), Amount: 2750},
+		{Time: date(2008, 10, 30), Amount: 4250},
+		{Time: date(2009, 2, 15), Amount: 3250},
+		{Time: date(2009, 4, 1), Amount: 2750},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(rate-0.373362535) > 1e-6 {
+		t.Errorf("xirr = %v, want 0.373362535", rate)
+	}
+
+	for name, flows := range map[string][]tracker.CashFlow{
+		"empty":     nil,
+		"only in":   {{Time: date(2021, 1, 1), Amount: -10}, {Time: date(2021, 6, 1), Amount: -10}},
+		"same time": {{Time: date(2021, 1, 1), Amount: -10}, {Time: date(2021, 1, 1), Amount: 20}},
+	} {
+		if _, err := tracker.XIRR(flows); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestComputeReturns(t *testing.T) {
+	// the price doubles, halves, then doubles again
+	small := []tracker.Purchase{
+		{Timestamp: date(2021, 1, 1), Fiat: 10, CRO: 100},
+		{Timestamp: date(2021, 4, 1), Fiat: 10, CRO: 50},
+		{Timestamp: date(2021, 7, 1), Fiat: 10, CRO: 100},
+	}
+	// the same prices, but most of the money goes in at the top
+	large := []tracker.Purchase{
+		{Timestamp: date(2021, 7, 1), Fiat: 10, CRO: 100},
+		{Timestamp: date(2021, 1, 1), Fiat: 10, CRO: 100},
+		{Timestamp: date(2021, 4, 1), Fiat: 1000, CRO: 5000},
+	}
+	at := date(2022, 1, 1)
+	smallReturns, err := tracker.ComputeReturns(small, 0.2, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	largeReturns, err := tracker.ComputeReturns(large, 0.2, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// time weighted returns only follow the price
+	for _, twr := range []float64{smallReturns.TWR, largeReturns.TWR} {
+		if math.Abs(twr-1) > 1e-9 {
+			t.Errorf("twr = %v, want 1", twr)
+		}
+	}
+	// money weighted returns punish buying the top
+	if largeReturns.XIRR >= smallReturns.XIRR {
+		t.Errorf("xirr buying the top = %v, want less than %v", largeReturns.XIRR, smallReturns.XIRR)
+	}
+	// and agree with the cash flows
+	if rate, _ := tracker.XIRR(tracker.CashFlows(small, 0.2, at)); rate != smallReturns.XIRR {
+		t.Errorf("xirr = %v, want %v", smallReturns.XIRR, rate)
+	}
+
+	if _, err := tracker.ComputeReturns(nil, 0.2, at); err == nil {
+		t.Error("expected no purchases to fail")
+	}
+}
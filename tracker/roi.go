@@ -0,0 +1,111 @@
+package tracker
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+type PurchaseEvent string
+type EarnEvent string
+
+func (p PurchaseEvent) String() string {
+	return string(p)
+}
+
+func (e EarnEvent) String() string {
+	return string(e)
+}
+
+// transaction descriptions used by the Crypto.com App
+const (
+	ReoccurringBuy PurchaseEvent = "Recurring Buy"
+	USDToCRO       PurchaseEvent = "USD -> CRO"
+	EURToCRO       PurchaseEvent = "EUR -> CRO"
+	BuyCRO         PurchaseEvent = "Buy CRO"
+	SignupBonus    EarnEvent     = "Sign-up Bonus Unlocked"
+	CryptoEarn     EarnEvent     = "Crypto Earn"
+)
+
+// Purchase is fiat spent on CRO.
+type Purchase struct {
+	Timestamp time.Time
+	Fiat      float64
+	CRO       float64
+}
+
+// Price is the fiat paid per CRO.
+func (p Purchase) Price() float64 {
+	if p.CRO == 0 {
+		return 0
+	}
+	return p.Fiat / p.CRO
+}
+
+// NewPurchase returns the purchase made by a transaction, if it is one that
+// can be valued in fiat.
+func NewPurchase(t Transaction, fiat string) (Purchase, bool) {
+	p := Purchase{Timestamp: t.Timestamp}
+	switch PurchaseEvent(t.Description) {
+	case ReoccurringBuy:
+		p.Fiat, p.CRO = t.Amount, t.ToAmount
+	case USDToCRO, EURToCRO:
+		p.Fiat, p.CRO = t.NativeAmount, t.ToAmount
+	case BuyCRO:
+		p.Fiat, p.CRO = t.NativeAmount, t.Amount
+	case ExchangeBuy:
+		// only buys of CRO with fiat or a stablecoin have a fiat value, and
+		// exchanges trade in several, so buys in another one are left out
+		if t.ToCurrency != "CRO" || !strings.EqualFold(t.NativeCurrency, fiat) {
+			return Purchase{}, false
+		}
+		p.Fiat, p.CRO = t.NativeAmount, t.ToAmount
+	default:
+		// TODO(igaskin): track earn events seperatly
+		return Purchase{}, false
+	}
+	p.Fiat, p.CRO = math.Abs(p.Fiat), math.Abs(p.CRO)
+	return p, true
+}
+
+// Purchases picks the CRO purchases made in fiat out of a list of
+// transactions.
+func Purchases(transactions []Transaction, fiat string) []Purchase {
+	var purchases []Purchase
+	for _, t := range transactions {
+		if p, ok := NewPurchase(t, fiat); ok {
+			purchases = append(purchases, p)
+		}
+	}
+	return purchases
+}
+
+// ROI summarizes the return on a set of purchases at a given CRO price.
+type ROI struct {
+	Invested     float64
+	CRO          float64
+	AveragePrice float64
+	Price        float64
+	Value        float64
+	Gain         float64
+	// Return is Gain as a fraction of Invested
+	Return float64
+}
+
+// ComputeROI values the purchases at price.
+func ComputeROI(purchases []Purchase, price float64) ROI {
+	roi := ROI{Price: price}
+	for _, p := range purchases {
+		roi.Invested += p.Fiat
+		roi.CRO += p.CRO
+	}
+	roi.Value = roi.CRO * price
+	roi.Gain = roi.Value - roi.Invested
+	if roi.CRO != 0 {
+		roi.AveragePrice = roi.Invested / roi.CRO
+	}
+	if roi.Invested != 0 {
+		roi.Return = roi.Gain / roi.Invested
+	}
+	return roi
+}
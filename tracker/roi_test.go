@@ -0,0 +1,35 @@
+package tracker_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+)
+
+// an exchange account trading CRO against both USDT and EUR
+const exchangeBuysCSV = `Timestamp (UTC),Transaction Description,Currency,Amount,To Currency,To Amount,Native Currency,Native Amount,Native Amount (in USD),Transaction Kind
+2021-03-01 10:00:00,Exchange Buy,USDT,-50,CRO,250,USD,50,50,exchange_trade
+2021-03-02 10:00:00,Exchange Buy,EUR,-40,CRO,200,EUR,40,48,exchange_trade
+2021-03-03 10:00:00,Exchange Buy,BTC,-0.001,CRO,300,,,,exchange_trade
+`
+
+func TestPurchasesInFiat(t *testing.T) {
+	transactions, err := tracker.ParseTransactions(strings.NewReader(exchangeBuysCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		fiat string
+		cro  float64
+	}{
+		{fiat: "USD", cro: 250},
+		{fiat: "eur", cro: 200},
+		{fiat: "GBP", cro: 0},
+	} {
+		roi := tracker.ComputeROI(tracker.Purchases(transactions, test.fiat), 1)
+		if roi.CRO != test.cro {
+			t.Errorf("%s: bought %g CRO, want %g", test.fiat, roi.CRO, test.cro)
+		}
+	}
+}
@@ -0,0 +1,842 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/sheets/v4"
+)
+
+// DefaultSpreadsheetTitle is the title of spreadsheets created by the importer.
+const DefaultSpreadsheetTitle = "Crypto Tracker"
+
+// columns of the ROI table, relative to the start column
+const (
+	fiatColumn int64 = iota
+	croColumn
+	purchasePriceColumn
+	percentChangeColumn
+	fiatChangeColumn
+	purchasedColumn
+	// returns that only make sense for the whole table, shown in the
+	// summary row
+	xirrColumn
+	twrColumn
+	tableWidth
+)
+
+type RowData struct {
+	Fiat float64
+	CRO  float64
+	// TODO: compute these from the other two values
+	PurchasePrice string
+	PercentChange string
+	FiatChange    string
+	Purchased     string
+}
+
+func (r *RowData) ToSlice() []interface{} {
+	e := reflect.ValueOf(r).Elem()
+	s := make([]interface{}, e.NumField())
+	for i := 0; i < e.NumField(); i++ {
+		s[i] = e.Field(i).Interface()
+	}
+	return s
+}
+
+// NewRowData lays out a purchase in the given sheet row, valuing it at
+// currentPrice, a number or a cell reference.
+func NewRowData(p Purchase, rowNumber, startColumn int64, currentPrice string) *RowData {
+	ref := func(column int64) string {
+		return Cell(startColumn+column, rowNumber)
+	}
+	return &RowData{
+		Fiat:          p.Fiat,
+		CRO:           p.CRO,
+		PurchasePrice: fmt.Sprintf("=(DIVIDE(%s,%s))", ref(fiatColumn), ref(croColumn)),
+		PercentChange: fmt.Sprintf("=(DIVIDE(MINUS(%[2]s,%[1]s),%[2]s))", ref(purchasePriceColumn), currentPrice),
+		FiatChange:    fmt.Sprintf("=MULTIPLY(%s,%s)", ref(fiatColumn), ref(percentChangeColumn)),
+		Purchased:     p.Timestamp.UTC().Format(TimestampLayout),
+	}
+}
+
+// TransactionImporter publishes the ROI of CRO purchases to a google sheet.
+type TransactionImporter struct {
+	Googlesheet   *sheets.Service
+	SpreadsheetID string
+	// Log receives progress messages
+	Log io.Writer
+
+	fiat              string
+	currentRow        int64
+	startRowIndex     int64
+	startColumnIndex  int64
+	sheetName         string
+	sheetID           int64
+	createSpreadsheet bool
+	pricesSheet       string
+	// row of the CRO price in the prices tab, 0 until it's been looked up
+	priceRow int64
+
+	// every transaction published by Sync, oldest first, and their keys
+	synced     []Transaction
+	syncedKeys map[string]bool
+	// purchases already in the sheet when Sync first ran
+	published []Purchase
+}
+
+type TransactionImporterOpts struct {
+	Googlesheet   *sheets.Service
+	SpreadsheetID string
+	SheetName     string
+	// StartRow and StartColumn anchor the top left cell of the table, e.g. 1
+	// and "A"
+	StartRow    int64
+	StartColumn string
+	Fiat        string
+	// CreateSpreadsheet starts a new spreadsheet when SpreadsheetID is empty
+	CreateSpreadsheet bool
+	// PricesSheet is the tab holding the price the table is valued at,
+	// DefaultPricesSheet when empty
+	PricesSheet string
+	// Log receives progress messages, discarded when nil
+	Log io.Writer
+}
+
+func NewTransactionImporter(opts TransactionImporterOpts) (*TransactionImporter, error) {
+	if opts.Googlesheet == nil {
+		return nil, fmt.Errorf("missing sheets service")
+	}
+	if opts.SpreadsheetID == "" && !opts.CreateSpreadsheet {
+		return nil, fmt.Errorf("Missing spreadsheet-id (pass --create-spreadsheet to start a new one)")
+	}
+	if opts.StartRow < 1 {
+		return nil, fmt.Errorf("invalid start row %d", opts.StartRow)
+	}
+	startColumnIndex, err := ColumnIndex(opts.StartColumn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start column: %w", err)
+	}
+	if opts.Log == nil {
+		opts.Log = ioutil.Discard
+	}
+	if opts.PricesSheet == "" {
+		opts.PricesSheet = DefaultPricesSheet
+	}
+	if opts.PricesSheet == opts.SheetName {
+		return nil, fmt.Errorf("the prices sheet can't be the table's sheet %q", opts.SheetName)
+	}
+	return &TransactionImporter{
+		Googlesheet:       opts.Googlesheet,
+		SpreadsheetID:     opts.SpreadsheetID,
+		Log:               opts.Log,
+		fiat:              opts.Fiat,
+		currentRow:        opts.StartRow,
+		startRowIndex:     opts.StartRow,
+		startColumnIndex:  startColumnIndex,
+		sheetName:         opts.SheetName,
+		createSpreadsheet: opts.CreateSpreadsheet,
+		pricesSheet:       opts.PricesSheet,
+	}, nil
+}
+
+// Import parses an export from r and publishes the ROI of its CRO purchases
+// at the current price.
+func (t *TransactionImporter) Import(ctx context.Context, r io.Reader, prices PriceProvider) error {
+	transactions, err := ParseTransactions(r)
+	if err != nil {
+		return err
+	}
+	return t.ImportTransactions(ctx, transactions, prices)
+}
+
+// ImportTransactions publishes the ROI of the CRO purchases among
+// transactions at the current price.
+func (t *TransactionImporter) ImportTransactions(ctx context.Context, transactions []Transaction, prices PriceProvider) error {
+	price, err := prices.Price(ctx, "CRO", t.fiat)
+	if err != nil {
+		return fmt.Errorf("failed to get CRO price; %w", err)
+	}
+	return t.Publish(ctx, Purchases(transactions, t.fiat), price)
+}
+
+// Sync merges the transactions of an export with the ones synced before and
+// republishes the table, oldest purchase first, when the export adds any.
+// Exports may overlap or only cover the latest weeks. Sync returns the added
+// transactions.
+func (t *TransactionImporter) Sync(ctx context.Context, r io.Reader, prices PriceProvider) ([]Transaction, error) {
+	transactions, err := ParseTransactions(r)
+	if err != nil {
+		return nil, err
+	}
+	if t.syncedKeys == nil {
+		if err := t.loadPublished(ctx); err != nil {
+			return nil, err
+		}
+	}
+	added, keys := t.unsynced(transactions)
+	if len(added) == 0 {
+		return nil, nil
+	}
+	merged := append(append([]Transaction(nil), t.synced...), added...)
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+
+	price, err := prices.Price(ctx, "CRO", t.fiat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CRO price; %w", err)
+	}
+	if err := t.Publish(ctx, t.withPublished(Purchases(merged, t.fiat)), price); err != nil {
+		return nil, err
+	}
+	t.synced = merged
+	for _, key := range keys {
+		t.syncedKeys[key] = true
+	}
+	return added, nil
+}
+
+// loadPublished reads the purchases an earlier run left in the sheet, so the
+// first Sync after a restart keeps the ones its export no longer covers.
+func (t *TransactionImporter) loadPublished(ctx context.Context) error {
+	t.syncedKeys = map[string]bool{}
+	if t.SpreadsheetID == "" {
+		return nil
+	}
+	if err := t.getSheetID(ctx); err != nil {
+		return err
+	}
+	_, written, err := t.readRows(ctx)
+	if err != nil {
+		return err
+	}
+	t.published, _ = tableRows(written)
+	if len(t.published) > 0 {
+		fmt.Fprintf(t.Log, "found %d purchases in %q\n", len(t.published), t.sheetName)
+	}
+	return nil
+}
+
+// withPublished adds the purchases found in the sheet that aren't among
+// purchases, oldest first.
+func (t *TransactionImporter) withPublished(purchases []Purchase) []Purchase {
+	merged := append([]Purchase(nil), purchases...)
+	for _, p := range t.published {
+		found := false
+		for _, q := range purchases {
+			if p.Timestamp.Equal(q.Timestamp) && p.Fiat == q.Fiat && p.CRO == q.CRO {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, p)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+	return merged
+}
+
+// unsynced picks the transactions Sync hasn't seen yet.
+func (t *TransactionImporter) unsynced(transactions []Transaction) (added []Transaction, keys []string) {
+	for i, key := range twinKeys(transactions) {
+		if t.syncedKeys[key] {
+			continue
+		}
+		added = append(added, transactions[i])
+		keys = append(keys, key)
+	}
+	return added, keys
+}
+
+// Publish writes price to the prices tab and one row per purchase, valued at
+// that price, followed by a summary row, then formats the table.
+func (t *TransactionImporter) Publish(ctx context.Context, purchases []Purchase, price float64) error {
+	if t.SpreadsheetID == "" {
+		if err := t.createNewSpreadsheet(ctx); err != nil {
+			return err
+		}
+	}
+	// the tab has to exist before any values can be written to it
+	if err := t.getSheetID(ctx); err != nil {
+		return err
+	}
+
+	// the rules of the last publish are replaced, not added to
+	staleRules, err := t.tableRuleRequests(ctx)
+	if err != nil {
+		return err
+	}
+
+	// a longer table written before leaves rows below the new one
+	_, written, err := t.readRows(ctx)
+	if err != nil {
+		return err
+	}
+	writtenEnd := t.startRowIndex
+	if len(written) > 0 {
+		previous, _ := tableRows(written)
+		writtenEnd += int64(len(previous)) + 2
+	}
+
+	currentPrice, err := t.quotePrice(ctx, price)
+	if err != nil {
+		return err
+	}
+
+	t.currentRow = t.startRowIndex
+	rows := [][]interface{}{
+		{t.fiat, "CRO", "CRO Price", "Percent Change", fmt.Sprintf("%s Change", t.fiat), "Purchased", "XIRR", "TWR"},
+	}
+	for i, p := range purchases {
+		rows = append(rows, NewRowData(p, t.startRowIndex+1+int64(i), t.startColumnIndex, currentPrice).ToSlice())
+	}
+	t.currentRow += int64(len(rows))
+
+	column := func(fn string, column int64) string {
+		return fmt.Sprintf("=%s(%s:%s)", fn, t.cell(column, t.startRowIndex), t.cell(column, t.currentRow-1))
+	}
+	rows = append(rows, []interface{}{
+		column("SUM", fiatColumn),
+		column("SUM", croColumn),
+		column("AVERAGE", purchasePriceColumn),
+		fmt.Sprintf("=MINUS(DIVIDE(SUM(%[1]s,%[2]s), ABS(%[1]s)),1)", t.cell(fiatColumn, t.currentRow), t.cell(fiatChangeColumn, t.currentRow)),
+		column("SUM", fiatChangeColumn),
+		"",
+	})
+	if len(purchases) > 0 {
+		rows[len(rows)-1] = append(rows[len(rows)-1], t.xirrFormula(currentPrice), t.twrFormula(currentPrice))
+	}
+	t.currentRow++
+
+	rangez := SheetRange(t.sheetName, t.cell(fiatColumn, t.startRowIndex))
+	_, err = t.Googlesheet.Spreadsheets.Values.Update(t.SpreadsheetID, rangez, &sheets.ValueRange{Values: rows}).ValueInputOption("USER_ENTERED").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(t.Log, "wrote %d purchases to %s\n", len(purchases), rangez)
+
+	requests := append(staleRules, t.formatRequests()...)
+	if writtenEnd > t.currentRow {
+		stale := SheetRange(t.sheetName, fmt.Sprintf("%s:%s", t.cell(fiatColumn, t.currentRow), t.cell(twrColumn, writtenEnd-1)))
+		if _, err := t.Googlesheet.Spreadsheets.Values.Clear(t.SpreadsheetID, stale, &sheets.ClearValuesRequest{}).Context(ctx).Do(); err != nil {
+			return fmt.Errorf("failed to clear %s; %w", stale, err)
+		}
+		requests = append(requests, t.clearFormatRequest(t.currentRow, writtenEnd))
+	}
+
+	// format data for readability
+	_, err = t.Googlesheet.Spreadsheets.BatchUpdate(t.SpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: requests,
+	}).Context(ctx).Do()
+	return err
+}
+
+// clearFormatRequest resets the format of the table columns from row start
+// up to end, such as the summary row of a longer table written before.
+func (t *TransactionImporter) clearFormatRequest(start, end int64) *sheets.Request {
+	return &sheets.Request{
+		RepeatCell: &sheets.RepeatCellRequest{
+			Range: &sheets.GridRange{
+				SheetId:          t.sheetID,
+				StartRowIndex:    start - 1,
+				EndRowIndex:      end - 1,
+				StartColumnIndex: t.startColumnIndex,
+				EndColumnIndex:   t.startColumnIndex + tableWidth,
+			},
+			Cell:   &sheets.CellData{},
+			Fields: "userEnteredFormat",
+		},
+	}
+}
+
+// tableRuleRequests deletes the conditional format rules an earlier publish
+// added to the table, recognised by the gain and loss columns they cover.
+func (t *TransactionImporter) tableRuleRequests(ctx context.Context) ([]*sheets.Request, error) {
+	spreadsheet, err := t.Googlesheet.Spreadsheets.Get(t.SpreadsheetID).
+		Fields(googleapi.Field("sheets(properties.sheetId,conditionalFormats)")).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spreadsheet %s; %w", t.SpreadsheetID, err)
+	}
+	var requests []*sheets.Request
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties == nil || sheet.Properties.SheetId != t.sheetID {
+			continue
+		}
+		// last first, so the indexes of the rest stay put
+		for i := len(sheet.ConditionalFormats) - 1; i >= 0; i-- {
+			if !t.isTableRule(sheet.ConditionalFormats[i]) {
+				continue
+			}
+			requests = append(requests, &sheets.Request{
+				DeleteConditionalFormatRule: &sheets.DeleteConditionalFormatRuleRequest{
+					SheetId: t.sheetID,
+					Index:   int64(i),
+				},
+			})
+		}
+	}
+	return requests, nil
+}
+
+// isTableRule tells the rules a publish adds apart from the user's: they
+// start at the gain and loss columns of the table, or span exactly the
+// table's gain, loss and return columns wherever an earlier start row or
+// column put them.
+func (t *TransactionImporter) isTableRule(rule *sheets.ConditionalFormatRule) bool {
+	for _, r := range rule.Ranges {
+		if r.StartColumnIndex == t.startColumnIndex+percentChangeColumn && r.StartRowIndex == t.startRowIndex-1 {
+			return true
+		}
+	}
+	if len(rule.Ranges) != 2 {
+		return false
+	}
+	changes, returns := rule.Ranges[0], rule.Ranges[1]
+	return changes.EndColumnIndex-changes.StartColumnIndex == fiatChangeColumn-percentChangeColumn+1 &&
+		returns.StartColumnIndex-changes.StartColumnIndex == xirrColumn-percentChangeColumn &&
+		returns.EndColumnIndex-returns.StartColumnIndex == twrColumn-xirrColumn+1 &&
+		returns.StartRowIndex == changes.StartRowIndex && returns.EndRowIndex == changes.EndRowIndex
+}
+
+// purchaseRange is the A1 range of a table column across the purchase rows,
+// once they are written.
+func (t *TransactionImporter) purchaseRange(column int64) string {
+	return fmt.Sprintf("%s:%s", t.cell(column, t.startRowIndex+1), t.cell(column, t.currentRow-1))
+}
+
+// xirrFormula is the money weighted return of the purchases, sold at
+// currentPrice today. The purchases are entered as positive and the sale as
+// negative, which gives the same rate.
+func (t *TransactionImporter) xirrFormula(currentPrice string) string {
+	return fmt.Sprintf("=XIRR({%s;MINUS(0,MULTIPLY(%s,%s))},{%s;NOW()})",
+		t.purchaseRange(fiatColumn), t.cell(croColumn, t.currentRow), currentPrice, t.purchaseRange(purchasedColumn))
+}
+
+// twrFormula is the time weighted return of the purchases, which for CRO
+// alone is the change from the price of the first purchase to currentPrice.
+func (t *TransactionImporter) twrFormula(currentPrice string) string {
+	purchased := t.purchaseRange(purchasedColumn)
+	return fmt.Sprintf("=MINUS(DIVIDE(%s,INDEX(%s,MATCH(MIN(%s),%s,0))),1)",
+		currentPrice, t.purchaseRange(purchasePriceColumn), purchased, purchased)
+}
+
+// cell is the A1 reference of a table column in the given row.
+func (t *TransactionImporter) cell(column, row int64) string {
+	return Cell(t.startColumnIndex+column, row)
+}
+
+// createNewSpreadsheet creates a spreadsheet containing only the target tab.
+func (t *TransactionImporter) createNewSpreadsheet(ctx context.Context) error {
+	spreadsheet, err := t.Googlesheet.Spreadsheets.Create(&sheets.Spreadsheet{
+		Properties: &sheets.SpreadsheetProperties{
+			Title: DefaultSpreadsheetTitle,
+		},
+		Sheets: []*sheets.Sheet{
+			{
+				Properties: &sheets.SheetProperties{
+					Title: t.sheetName,
+				},
+			},
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to create spreadsheet; %w", err)
+	}
+	t.SpreadsheetID = spreadsheet.SpreadsheetId
+	fmt.Fprintf(t.Log, "created spreadsheet %s\n", spreadsheet.SpreadsheetUrl)
+	fmt.Fprintf(t.Log, "reuse it with `crypto-tracker config set spreadsheet-id %s`\n", spreadsheet.SpreadsheetId)
+	return nil
+}
+
+// getSheetID looks up the id of the target tab, adding the tab when the
+// spreadsheet doesn't have it yet.
+func (t *TransactionImporter) getSheetID(ctx context.Context) error {
+	sheetID, _, err := t.ensureSheet(ctx, t.sheetName)
+	if err != nil {
+		return err
+	}
+	t.sheetID = sheetID
+	return nil
+}
+
+// ensureSheet returns the id of the tab with the given title, adding the tab
+// when the spreadsheet doesn't have it yet.
+func (t *TransactionImporter) ensureSheet(ctx context.Context, title string) (sheetID int64, added bool, err error) {
+	spreadsheet, err := t.Googlesheet.Spreadsheets.Get(t.SpreadsheetID).Fields(googleapi.Field("sheets.properties")).Context(ctx).Do()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get spreadsheet %s; %w", t.SpreadsheetID, err)
+	}
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.Title == title {
+			return sheet.Properties.SheetId, false, nil
+		}
+	}
+
+	resp, err := t.Googlesheet.Spreadsheets.BatchUpdate(t.SpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{
+			{
+				AddSheet: &sheets.AddSheetRequest{
+					Properties: &sheets.SheetProperties{
+						Title: title,
+					},
+				},
+			},
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to add sheet %q; %w", title, err)
+	}
+	fmt.Fprintf(t.Log, "added sheet %q\n", title)
+	return resp.Replies[0].AddSheet.Properties.SheetId, true, nil
+}
+
+func (t *TransactionImporter) formatRequests() []*sheets.Request {
+	return []*sheets.Request{
+		{
+			// format fiat as currency
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: &sheets.GridRange{
+					StartColumnIndex: t.startColumnIndex + fiatColumn,
+					EndColumnIndex:   t.startColumnIndex + fiatColumn + 1,
+					StartRowIndex:    t.startRowIndex - 1,
+					EndRowIndex:      t.currentRow - 1,
+					SheetId:          t.sheetID,
+				},
+				Cell: &sheets.CellData{
+					UserEnteredFormat: &sheets.CellFormat{
+						NumberFormat: &sheets.NumberFormat{
+							Type: "CURRENCY",
+						},
+					},
+				},
+				Fields: "userEnteredFormat.numberFormat",
+			},
+		},
+		{
+			// format fiat as currency
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: &sheets.GridRange{
+					StartColumnIndex: t.startColumnIndex + fiatChangeColumn,
+					EndColumnIndex:   t.startColumnIndex + fiatChangeColumn + 1,
+					StartRowIndex:    t.startRowIndex - 1,
+					EndRowIndex:      t.currentRow - 1,
+					SheetId:          t.sheetID,
+				},
+				Cell: &sheets.CellData{
+					UserEnteredFormat: &sheets.CellFormat{
+						NumberFormat: &sheets.NumberFormat{
+							Type: "CURRENCY",
+						},
+					},
+				},
+				Fields: "userEnteredFormat.numberFormat",
+			},
+		},
+		{
+			// format cro purchase price as currency
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: &sheets.GridRange{
+					StartColumnIndex: t.startColumnIndex + purchasePriceColumn,
+					EndColumnIndex:   t.startColumnIndex + purchasePriceColumn + 1,
+					StartRowIndex:    t.startRowIndex - 1,
+					EndRowIndex:      t.currentRow - 1,
+					SheetId:          t.sheetID,
+				},
+				Cell: &sheets.CellData{
+					UserEnteredFormat: &sheets.CellFormat{
+						NumberFormat: &sheets.NumberFormat{
+							Type: "CURRENCY",
+						},
+					},
+				},
+				Fields: "userEnteredFormat.numberFormat",
+			},
+		},
+		{
+			// format CRO as a float
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: &sheets.GridRange{
+					StartColumnIndex: t.startColumnIndex + croColumn,
+					EndColumnIndex:   t.startColumnIndex + croColumn + 1,
+					StartRowIndex:    t.startRowIndex - 1,
+					EndRowIndex:      t.currentRow - 1,
+					SheetId:          t.sheetID,
+				},
+				Cell: &sheets.CellData{
+					UserEnteredFormat: &sheets.CellFormat{
+						NumberFormat: &sheets.NumberFormat{
+							Type:    "NUMBER",
+							Pattern: "#,##0.00",
+						},
+					},
+				},
+				Fields: "userEnteredFormat.numberFormat",
+			},
+		},
+		{
+			// format change as percentage
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: &sheets.GridRange{
+					StartColumnIndex: t.startColumnIndex + percentChangeColumn,
+					EndColumnIndex:   t.startColumnIndex + percentChangeColumn + 1,
+					StartRowIndex:    t.startRowIndex - 1,
+					EndRowIndex:      t.currentRow - 1,
+					SheetId:          t.sheetID,
+				},
+				Cell: &sheets.CellData{
+					UserEnteredFormat: &sheets.CellFormat{
+						NumberFormat: &sheets.NumberFormat{
+							Type:    "PERCENT",
+							Pattern: "#.0#%",
+						},
+					},
+				},
+				Fields: "userEnteredFormat.numberFormat",
+			},
+		},
+		{
+			// format returns as percentage
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: &sheets.GridRange{
+					StartColumnIndex: t.startColumnIndex + xirrColumn,
+					EndColumnIndex:   t.startColumnIndex + twrColumn + 1,
+					StartRowIndex:    t.startRowIndex - 1,
+					EndRowIndex:      t.currentRow - 1,
+					SheetId:          t.sheetID,
+				},
+				Cell: &sheets.CellData{
+					UserEnteredFormat: &sheets.CellFormat{
+						NumberFormat: &sheets.NumberFormat{
+							Type:    "PERCENT",
+							Pattern: "#.0#%",
+						},
+					},
+				},
+				Fields: "userEnteredFormat.numberFormat",
+			},
+		},
+		{
+			// format purchase time as a date
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: &sheets.GridRange{
+					StartColumnIndex: t.startColumnIndex + purchasedColumn,
+					EndColumnIndex:   t.startColumnIndex + purchasedColumn + 1,
+					StartRowIndex:    t.startRowIndex - 1,
+					EndRowIndex:      t.currentRow - 1,
+					SheetId:          t.sheetID,
+				},
+				Cell: &sheets.CellData{
+					UserEnteredFormat: &sheets.CellFormat{
+						NumberFormat: &sheets.NumberFormat{
+							Type:    "DATE_TIME",
+							Pattern: "yyyy-mm-dd hh:mm",
+						},
+					},
+				},
+				Fields: "userEnteredFormat.numberFormat",
+			},
+		},
+		{
+			// set font family
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: &sheets.GridRange{
+					StartColumnIndex: t.startColumnIndex,
+					EndColumnIndex:   t.startColumnIndex + tableWidth,
+					StartRowIndex:    t.startRowIndex - 1,
+					EndRowIndex:      t.currentRow - 1,
+					SheetId:          t.sheetID,
+				},
+				Cell: &sheets.CellData{
+					UserEnteredFormat: &sheets.CellFormat{
+						TextFormat: &sheets.TextFormat{
+							FontFamily: "Inconsolata",
+							FontSize:   11,
+						},
+					},
+				},
+				Fields: "userEnteredFormat.textFormat",
+			},
+		},
+		// clear any existing boarders
+		{
+			UpdateBorders: &sheets.UpdateBordersRequest{
+				Range: &sheets.GridRange{
+					StartColumnIndex: t.startColumnIndex,
+					EndColumnIndex:   t.startColumnIndex + tableWidth,
+					StartRowIndex:    t.startRowIndex - 1,
+					EndRowIndex:      t.currentRow - 1,
+					SheetId:          t.sheetID,
+				},
+				Top: &sheets.Border{
+					Style: "NONE",
+				},
+				InnerHorizontal: &sheets.Border{
+					Style: "NONE",
+				},
+				Bottom: &sheets.Border{
+					Style: "NONE",
+				},
+				InnerVertical: &sheets.Border{
+					Style: "NONE",
+				},
+				Left: &sheets.Border{
+					Style: "NONE",
+				},
+				Right: &sheets.Border{
+					Style: "NONE",
+				},
+			},
+		},
+		// add border to footer
+		{
+			UpdateBorders: &sheets.UpdateBordersRequest{
+				Range: &sheets.GridRange{
+					StartColumnIndex: t.startColumnIndex,
+					EndColumnIndex:   t.startColumnIndex + tableWidth,
+					StartRowIndex:    t.currentRow - 2,
+					EndRowIndex:      t.currentRow - 1,
+					SheetId:          t.sheetID,
+				},
+				Top: &sheets.Border{
+					Style: "SOLID",
+					Color: &sheets.Color{
+						Red:   0,
+						Green: 0,
+						Blue:  0,
+					},
+				},
+			},
+		},
+		// add border to header
+		{
+			UpdateBorders: &sheets.UpdateBordersRequest{
+				Range: &sheets.GridRange{
+					StartColumnIndex: t.startColumnIndex,
+					EndColumnIndex:   t.startColumnIndex + tableWidth,
+					StartRowIndex:    t.startRowIndex - 1,
+					EndRowIndex:      t.startRowIndex,
+					SheetId:          t.sheetID,
+				},
+				Bottom: &sheets.Border{
+					Style: "SOLID",
+					Color: &sheets.Color{
+						Red:   0,
+						Green: 0,
+						Blue:  0,
+					},
+				},
+			},
+		},
+		{
+			// bold the summary row
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: &sheets.GridRange{
+					StartColumnIndex: t.startColumnIndex,
+					EndColumnIndex:   t.startColumnIndex + tableWidth,
+					StartRowIndex:    t.currentRow - 2,
+					EndRowIndex:      t.currentRow - 1,
+					SheetId:          t.sheetID,
+				},
+				Cell: &sheets.CellData{
+					UserEnteredFormat: &sheets.CellFormat{
+						TextFormat: &sheets.TextFormat{
+							Bold: true,
+						},
+					},
+				},
+				Fields: "userEnteredFormat.textFormat.bold",
+			},
+		},
+		{
+			// conditional formatting gains/losses
+			AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
+				Index: 0,
+				Rule: &sheets.ConditionalFormatRule{
+					Ranges: []*sheets.GridRange{
+						{
+							StartColumnIndex: t.startColumnIndex + percentChangeColumn,
+							EndColumnIndex:   t.startColumnIndex + fiatChangeColumn + 1,
+							StartRowIndex:    t.startRowIndex - 1,
+							EndRowIndex:      t.currentRow - 1,
+							SheetId:          t.sheetID,
+						},
+						{
+							StartColumnIndex: t.startColumnIndex + xirrColumn,
+							EndColumnIndex:   t.startColumnIndex + twrColumn + 1,
+							StartRowIndex:    t.startRowIndex - 1,
+							EndRowIndex:      t.currentRow - 1,
+							SheetId:          t.sheetID,
+						},
+					},
+					BooleanRule: &sheets.BooleanRule{
+						Condition: &sheets.BooleanCondition{
+							Type: "NUMBER_GREATER",
+							Values: []*sheets.ConditionValue{
+								{
+									UserEnteredValue: "0",
+								},
+							},
+						},
+						Format: &sheets.CellFormat{
+							BackgroundColor: &sheets.Color{
+								Red:   0.850,
+								Green: 0.917,
+								Blue:  0.827,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			// conditional formatting gains/losses
+			AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
+				Index: 0,
+				Rule: &sheets.ConditionalFormatRule{
+					Ranges: []*sheets.GridRange{
+						{
+							StartColumnIndex: t.startColumnIndex + percentChangeColumn,
+							EndColumnIndex:   t.startColumnIndex + fiatChangeColumn + 1,
+							StartRowIndex:    t.startRowIndex - 1,
+							EndRowIndex:      t.currentRow - 1,
+							SheetId:          t.sheetID,
+						},
+						{
+							StartColumnIndex: t.startColumnIndex + xirrColumn,
+							EndColumnIndex:   t.startColumnIndex + twrColumn + 1,
+							StartRowIndex:    t.startRowIndex - 1,
+							EndRowIndex:      t.currentRow - 1,
+							SheetId:          t.sheetID,
+						},
+					},
+					BooleanRule: &sheets.BooleanRule{
+						Condition: &sheets.BooleanCondition{
+							Type: "NUMBER_LESS_THAN_EQ",
+							Values: []*sheets.ConditionValue{
+								{
+									UserEnteredValue: "0",
+								},
+							},
+						},
+						Format: &sheets.CellFormat{
+							BackgroundColor: &sheets.Color{
+								Red:   0.956,
+								Green: 0.8,
+								Blue:  0.8,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
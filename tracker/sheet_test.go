@@ -0,0 +1,380 @@
+package tracker_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/igaskin/crypto-tracker/tracker/sheetstest"
+	"google.golang.org/api/sheets/v4"
+)
+
+const transactionsCSV = `Timestamp (UTC),Transaction Description,Currency,Amount,To Currency,To Amount,Native Currency,Native Amount,Native Amount (in USD),Transaction Kind
+2021-03-01 10:00:00,Recurring Buy,USD,-50,CRO,300,USD,50,50,recurring_buy_order
+2021-03-02 10:00:00,Crypto Earn,CRO,2.5,,,USD,0.4,0.4,crypto_earn_interest_paid
+2021-03-03 10:00:00,USD -> CRO,USD,-20,CRO,100,USD,20,20,viban_purchase
+2021-03-04 10:00:00,Withdraw CRO,CRO,-200,,,USD,30,30,crypto_withdrawal
+`
+
+type fixedPrice float64
+
+func (p fixedPrice) Price(ctx context.Context, asset, fiat string) (float64, error) {
+	return float64(p), nil
+}
+
+func newImporter(t *testing.T, server *sheetstest.Server, opts tracker.TransactionImporterOpts) *tracker.TransactionImporter {
+	t.Helper()
+	service, err := server.Service(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Googlesheet = service
+	if opts.SheetName == "" {
+		opts.SheetName = "ROI"
+	}
+	if opts.StartRow == 0 {
+		opts.StartRow = 1
+	}
+	if opts.StartColumn == "" {
+		opts.StartColumn = "A"
+	}
+	if opts.Fiat == "" {
+		opts.Fiat = "USD"
+	}
+	importer, err := tracker.NewTransactionImporter(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return importer
+}
+
+func TestImportWritesGrid(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI")
+
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet"})
+	if err := importer.Import(context.Background(), strings.NewReader(transactionsCSV), fixedPrice(0.25)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"USD", "CRO", "CRO Price", "Percent Change", "USD Change", "Purchased", "XIRR", "TWR"},
+		{"50", "300", "=(DIVIDE(A2,B2))", "=(DIVIDE(MINUS(Prices!$C$2,C2),Prices!$C$2))", "=MULTIPLY(A2,D2)", "2021-03-01 10:00:00", "", ""},
+		{"20", "100", "=(DIVIDE(A3,B3))", "=(DIVIDE(MINUS(Prices!$C$2,C3),Prices!$C$2))", "=MULTIPLY(A3,D3)", "2021-03-03 10:00:00", "", ""},
+		{
+			"=SUM(A1:A3)", "=SUM(B1:B3)", "=AVERAGE(C1:C3)", "=MINUS(DIVIDE(SUM(A4,E4), ABS(A4)),1)", "=SUM(E1:E3)", "",
+			"=XIRR({A2:A3;MINUS(0,MULTIPLY(B4,Prices!$C$2))},{F2:F3;NOW()})",
+			"=MINUS(DIVIDE(Prices!$C$2,INDEX(C2:C3,MATCH(MIN(F2:F3),F2:F3,0))),1)",
+		},
+	}
+	if got := server.Grid("sheet", "ROI"); !reflect.DeepEqual(got, want) {
+		t.Errorf("grid = %q, want %q", got, want)
+	}
+
+	prices := server.Grid("sheet", tracker.DefaultPricesSheet)
+	if len(prices) != 2 {
+		t.Fatalf("prices = %q, want a header and the CRO price", prices)
+	}
+	if got, want := prices[1][:3], []string{"CRO", "USD", "0.25"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("price row = %q, want %q", got, want)
+	}
+	if _, err := time.Parse(tracker.TimestampLayout, prices[1][3]); err != nil {
+		t.Errorf("fetched at: %v", err)
+	}
+}
+
+func TestImportFormatsTable(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "Other", "ROI")
+
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{
+		SpreadsheetID: "sheet",
+		StartRow:      10,
+		StartColumn:   "AA",
+	})
+	if err := importer.Import(context.Background(), strings.NewReader(transactionsCSV), fixedPrice(0.25)); err != nil {
+		t.Fatal(err)
+	}
+
+	numberFormats := map[string]string{
+		"ROI!AA11": "CURRENCY",
+		"ROI!AB11": "NUMBER",
+		"ROI!AC12": "CURRENCY",
+		"ROI!AD13": "PERCENT",
+		"ROI!AE13": "CURRENCY",
+	}
+	for ref, want := range numberFormats {
+		c := server.Cell("sheet", ref)
+		if c == nil || c.UserEnteredFormat == nil || c.UserEnteredFormat.NumberFormat == nil {
+			t.Errorf("%s has no number format", ref)
+			continue
+		}
+		if got := c.UserEnteredFormat.NumberFormat.Type; got != want {
+			t.Errorf("%s number format = %s, want %s", ref, got, want)
+		}
+	}
+
+	summary := server.Cell("sheet", "ROI!AA13")
+	if summary == nil || summary.UserEnteredFormat == nil || summary.UserEnteredFormat.TextFormat == nil {
+		t.Fatalf("summary row has no text format: %+v", summary)
+	}
+	if text := summary.UserEnteredFormat.TextFormat; !text.Bold || text.FontFamily != "Inconsolata" {
+		t.Errorf("summary row text format = %+v, want bold Inconsolata", text)
+	}
+	if c := server.Cell("sheet", "ROI!AA14"); c != nil {
+		t.Errorf("formatted past the summary row: %+v", c)
+	}
+	if c := server.Cell("sheet", "ROI!A1"); c != nil {
+		t.Errorf("wrote outside the table: %+v", c)
+	}
+
+	spreadsheet := server.Spreadsheet("sheet")
+	roi := spreadsheet.Sheets[1]
+	for _, rule := range roi.ConditionalFormats {
+		for _, r := range rule.Ranges {
+			if r.SheetId != roi.Properties.SheetId {
+				t.Errorf("conditional format targets sheet %d, want %d", r.SheetId, roi.Properties.SheetId)
+			}
+		}
+	}
+	if len(roi.ConditionalFormats) == 0 {
+		t.Error("no conditional format rules")
+	}
+	if len(spreadsheet.Sheets[0].ConditionalFormats) != 0 {
+		t.Error("formatted the wrong sheet")
+	}
+
+	footer := server.Cell("sheet", "ROI!AC13")
+	if footer == nil || footer.UserEnteredFormat == nil || footer.UserEnteredFormat.Borders == nil || footer.UserEnteredFormat.Borders.Bottom == nil {
+		t.Errorf("footer has no bottom border: %+v", footer)
+	}
+}
+
+func TestImportAddsMissingSheet(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "Sheet1")
+
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet", SheetName: "My ROI"})
+	if err := importer.Import(context.Background(), strings.NewReader(transactionsCSV), fixedPrice(1)); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.Grid("sheet", "My ROI"); len(got) != 4 {
+		t.Errorf("wrote %d rows to the new sheet, want 4", len(got))
+	}
+	if got := server.Grid("sheet", "Sheet1"); len(got) != 0 {
+		t.Errorf("wrote %d rows to the existing sheet, want 0", len(got))
+	}
+}
+
+func TestImportCreatesSpreadsheet(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{CreateSpreadsheet: true})
+	if err := importer.Import(context.Background(), strings.NewReader(transactionsCSV), fixedPrice(1)); err != nil {
+		t.Fatal(err)
+	}
+	spreadsheet := server.Spreadsheet(importer.SpreadsheetID)
+	if spreadsheet == nil {
+		t.Fatalf("spreadsheet %q wasn't created", importer.SpreadsheetID)
+	}
+	if spreadsheet.Properties.Title != tracker.DefaultSpreadsheetTitle {
+		t.Errorf("title = %q, want %q", spreadsheet.Properties.Title, tracker.DefaultSpreadsheetTitle)
+	}
+	if got := server.Grid(importer.SpreadsheetID, "ROI"); len(got) != 4 {
+		t.Errorf("wrote %d rows, want 4", len(got))
+	}
+}
+
+func TestImportMissingSpreadsheet(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "missing"})
+	if err := importer.Import(context.Background(), strings.NewReader(transactionsCSV), fixedPrice(1)); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestSyncIsIncremental(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI")
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet"})
+	ctx := context.Background()
+
+	const header = "Timestamp (UTC),Transaction Description,Currency,Amount,To Currency,To Amount,Native Currency,Native Amount,Native Amount (in USD),Transaction Kind\n"
+	// exports list the newest transaction first
+	first := header +
+		"2021-03-08 08:00:00,Recurring Buy,USD,-25,CRO,100,USD,25,25,recurring_buy_order\n" +
+		"2021-03-02 10:00:00,Card Cashback,CRO,1,,,USD,0.25,0.25,referral_card_cashback\n" +
+		"2021-03-02 10:00:00,Card Cashback,CRO,1,,,USD,0.25,0.25,referral_card_cashback\n" +
+		"2021-03-01 08:00:00,Recurring Buy,USD,-25,CRO,125,USD,25,25,recurring_buy_order\n"
+	added, err := importer.Sync(ctx, strings.NewReader(first), fixedPrice(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 4 {
+		t.Errorf("first sync added %d transactions, want 4", len(added))
+	}
+
+	// the next export only overlaps the last week
+	second := header +
+		"2021-03-15 08:00:00,Recurring Buy,USD,-25,CRO,50,USD,25,25,recurring_buy_order\n" +
+		"2021-03-09 10:00:00,Card Cashback,CRO,1,,,USD,0.25,0.25,referral_card_cashback\n" +
+		"2021-03-08 08:00:00,Recurring Buy,USD,-25,CRO,100,USD,25,25,recurring_buy_order\n"
+	added, err = importer.Sync(ctx, strings.NewReader(second), fixedPrice(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var descriptions []string
+	for _, tx := range added {
+		descriptions = append(descriptions, tx.Timestamp.Format("01-02")+" "+tx.Description)
+	}
+	if want := []string{"03-15 Recurring Buy", "03-09 Card Cashback"}; !reflect.DeepEqual(descriptions, want) {
+		t.Errorf("second sync added %q, want %q", descriptions, want)
+	}
+
+	var cro []string
+	for _, row := range server.Grid("sheet", "ROI")[1:] {
+		cro = append(cro, row[1])
+	}
+	if want := []string{"125", "100", "50", "=SUM(B1:B4)"}; !reflect.DeepEqual(cro, want) {
+		t.Errorf("CRO column = %q, want %q", cro, want)
+	}
+
+	// re-syncing an export changes nothing
+	added, err = importer.Sync(ctx, strings.NewReader(first), fixedPrice(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 0 {
+		t.Errorf("re-sync added %d transactions, want 0", len(added))
+	}
+
+	// after a restart, the purchases the sheet holds are kept
+	restarted := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet"})
+	third := header +
+		"2021-03-22 08:00:00,Recurring Buy,USD,-25,CRO,40,USD,25,25,recurring_buy_order\n" +
+		"2021-03-15 08:00:00,Recurring Buy,USD,-25,CRO,50,USD,25,25,recurring_buy_order\n"
+	if _, err := restarted.Sync(ctx, strings.NewReader(third), fixedPrice(0.5)); err != nil {
+		t.Fatal(err)
+	}
+	cro = nil
+	for _, row := range server.Grid("sheet", "ROI")[1:] {
+		cro = append(cro, row[1])
+	}
+	if want := []string{"125", "100", "50", "40", "=SUM(B1:B5)"}; !reflect.DeepEqual(cro, want) {
+		t.Errorf("CRO column after a restart = %q, want %q", cro, want)
+	}
+}
+
+func TestPublishReplacesTable(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI")
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet"})
+	ctx := context.Background()
+
+	// a rule of the user's own, outside the table
+	service, err := server.Service(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.Spreadsheets.BatchUpdate("sheet", &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{
+			AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
+				Rule: &sheets.ConditionalFormatRule{
+					Ranges: []*sheets.GridRange{{SheetId: server.Spreadsheet("sheet").Sheets[0].Properties.SheetId, StartColumnIndex: 20, EndColumnIndex: 21}},
+					BooleanRule: &sheets.BooleanRule{
+						Condition: &sheets.BooleanCondition{Type: "NOT_BLANK"},
+						Format:    &sheets.CellFormat{TextFormat: &sheets.TextFormat{Bold: true}},
+					},
+				},
+			},
+		}},
+	}).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// and notes of the user's own, below the table
+	_, err = service.Spreadsheets.Values.Update("sheet", "ROI!A10", &sheets.ValueRange{Values: [][]interface{}{{"notes"}}}).
+		ValueInputOption("RAW").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transactions, err := tracker.ParseTransactions(strings.NewReader(transactionsCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	purchases := tracker.Purchases(transactions, "USD")
+	if err := importer.Publish(ctx, purchases, 0.25); err != nil {
+		t.Fatal(err)
+	}
+	// a shorter table, such as after a purchase left the export
+	if err := importer.Publish(ctx, purchases[:1], 0.25); err != nil {
+		t.Fatal(err)
+	}
+
+	if rules := server.Spreadsheet("sheet").Sheets[0].ConditionalFormats; len(rules) != 3 {
+		t.Errorf("sheet has %d conditional format rules, want the table's 2 and the user's", len(rules))
+	}
+	grid := server.Grid("sheet", "ROI")
+	if len(grid) < 3 || grid[2][0] != "=SUM(A1:A2)" {
+		t.Fatalf("grid = %q, want a purchase and its summary row", grid)
+	}
+	for i, row := range grid[3:9] {
+		for _, value := range row {
+			if value != "" {
+				t.Errorf("row %d below the summary row = %q, want it cleared", i+4, row)
+				break
+			}
+		}
+	}
+	if len(grid) < 10 || grid[9][0] != "notes" {
+		t.Errorf("grid = %q, want the user's notes kept", grid)
+	}
+	// the summary row of the longer table loses its bold text and borders
+	if stale := server.Cell("sheet", "ROI!A4"); stale != nil && stale.UserEnteredFormat != nil {
+		t.Errorf("old summary row format = %+v, want it reset", stale.UserEnteredFormat)
+	}
+}
+
+func TestPublishMovedTable(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI")
+	ctx := context.Background()
+	transactions, err := tracker.ParseTransactions(strings.NewReader(transactionsCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	purchases := tracker.Purchases(transactions, "USD")
+
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet"})
+	if err := importer.Publish(ctx, purchases, 0.25); err != nil {
+		t.Fatal(err)
+	}
+	// the start cell changed between publishes
+	moved := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet", StartRow: 3, StartColumn: "B"})
+	if err := moved.Publish(ctx, purchases, 0.25); err != nil {
+		t.Fatal(err)
+	}
+
+	rules := server.Spreadsheet("sheet").Sheets[0].ConditionalFormats
+	if len(rules) != 2 {
+		t.Fatalf("sheet has %d conditional format rules, want the moved table's 2", len(rules))
+	}
+	for _, rule := range rules {
+		if r := rule.Ranges[0]; r.StartRowIndex != 2 || r.StartColumnIndex != 4 {
+			t.Errorf("rule starts at row %d, column %d, want the moved table's", r.StartRowIndex, r.StartColumnIndex)
+		}
+	}
+}
@@ -0,0 +1,998 @@
+// Package sheetstest provides an in-process fake of the subset of the Google
+// Sheets v4 REST API used by crypto-tracker, for tests that shouldn't need a
+// google account.
+package sheetstest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// Server is a fake Sheets API keeping every spreadsheet in memory. It serves
+//
+//	POST /v4/spreadsheets                            (spreadsheets.create)
+//	GET  /v4/spreadsheets/{id}                       (spreadsheets.get)
+//	POST /v4/spreadsheets/{id}:batchUpdate           (spreadsheets.batchUpdate)
+//	GET  /v4/spreadsheets/{id}/values/{range}        (values.get)
+//	PUT  /v4/spreadsheets/{id}/values/{range}        (values.update)
+//	POST /v4/spreadsheets/{id}/values/{range}:append (values.append)
+//	POST /v4/spreadsheets/{id}/values/{range}:clear  (values.clear)
+//	POST /v4/spreadsheets/{id}/values:batchUpdate    (values.batchUpdate)
+//
+// batchUpdate understands addSheet, repeatCell, updateBorders,
+// addConditionalFormatRule, deleteConditionalFormatRule and addChart
+// requests, and values.get only renders FORMULA
+// values since the fake doesn't evaluate formulas; anything else is rejected
+// with a 400 so a test notices the fake falling behind the code under test.
+type Server struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	spreadsheets map[string]*sheets.Spreadsheet
+	created      int
+}
+
+// NewServer starts a fake with no spreadsheets. Callers should Close it.
+func NewServer() *Server {
+	s := &Server{
+		spreadsheets: map[string]*sheets.Spreadsheet{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Service returns a sheets client talking to the fake.
+func (s *Server) Service(ctx context.Context) (*sheets.Service, error) {
+	return sheets.NewService(ctx,
+		option.WithEndpoint(s.URL+"/"),
+		option.WithHTTPClient(s.Client()),
+	)
+}
+
+// AddSpreadsheet creates an empty spreadsheet with one tab per title.
+func (s *Server) AddSpreadsheet(id string, titles ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	spreadsheet := &sheets.Spreadsheet{
+		SpreadsheetId: id,
+		Properties:    &sheets.SpreadsheetProperties{Title: id},
+	}
+	for _, title := range titles {
+		addSheet(spreadsheet, &sheets.SheetProperties{Title: title})
+	}
+	s.spreadsheets[id] = spreadsheet
+}
+
+// Spreadsheet returns a copy of a spreadsheet, including its grid data, or
+// nil when it doesn't exist.
+func (s *Server) Spreadsheet(id string) *sheets.Spreadsheet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	spreadsheet, ok := s.spreadsheets[id]
+	if !ok {
+		return nil
+	}
+	return clone(spreadsheet)
+}
+
+// Cell returns a copy of the cell at an A1 reference such as "ROI!B2", or nil
+// when nothing was written to it.
+func (s *Server) Cell(spreadsheetID, ref string) *sheets.CellData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	spreadsheet, ok := s.spreadsheets[spreadsheetID]
+	if !ok {
+		return nil
+	}
+	sheet, row, column, err := parseRange(spreadsheet, ref)
+	if err != nil {
+		return nil
+	}
+	data := grid(sheet)
+	if row >= int64(len(data.RowData)) || column >= int64(len(data.RowData[row].Values)) {
+		return nil
+	}
+	var cell sheets.CellData
+	copyJSON(data.RowData[row].Values[column], &cell)
+	return &cell
+}
+
+// Grid renders the values of a tab the way they were entered: formulas as
+// written, numbers in their shortest form and booleans as TRUE or FALSE.
+// Empty cells are "".
+func (s *Server) Grid(spreadsheetID, title string) [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	spreadsheet, ok := s.spreadsheets[spreadsheetID]
+	if !ok {
+		return nil
+	}
+	sheet := sheetByTitle(spreadsheet, title)
+	if sheet == nil {
+		return nil
+	}
+	var rows [][]string
+	for _, rowData := range grid(sheet).RowData {
+		row := make([]string, len(rowData.Values))
+		for i, cell := range rowData.Values {
+			row[i] = FormatValue(cell.UserEnteredValue)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// FormatValue renders a cell value the way it would be typed into the sheet.
+func FormatValue(v *sheets.ExtendedValue) string {
+	switch {
+	case v == nil:
+		return ""
+	case v.FormulaValue != nil:
+		return *v.FormulaValue
+	case v.NumberValue != nil:
+		return strconv.FormatFloat(*v.NumberValue, 'f', -1, 64)
+	case v.BoolValue != nil:
+		return strings.ToUpper(strconv.FormatBool(*v.BoolValue))
+	case v.StringValue != nil:
+		return *v.StringValue
+	}
+	return ""
+}
+
+// apiError is returned by handlers and rendered as a google API error body.
+type apiError struct {
+	code    int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func errorf(code int, format string, a ...interface{}) error {
+	return &apiError{code: code, message: fmt.Sprintf(format, a...)}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.route(r)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if e, ok := err.(*apiError); ok {
+			code = e.code
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    code,
+				"message": err.Error(),
+				"status":  http.StatusText(code),
+			},
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) route(r *http.Request) (interface{}, error) {
+	path := r.URL.EscapedPath()
+	if !strings.HasPrefix(path, "/v4/spreadsheets") {
+		return nil, errorf(http.StatusNotFound, "unknown path %s", r.URL.Path)
+	}
+	rest := strings.TrimPrefix(path, "/v4/spreadsheets")
+	if rest == "" {
+		if r.Method != http.MethodPost {
+			return nil, errorf(http.StatusMethodNotAllowed, "%s not allowed", r.Method)
+		}
+		return s.create(r)
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(rest, "/"), "/", 3)
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "invalid path %s", path)
+		}
+		parts[i] = unescaped
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case len(parts) == 1 && strings.HasSuffix(parts[0], ":batchUpdate") && r.Method == http.MethodPost:
+		return s.batchUpdate(strings.TrimSuffix(parts[0], ":batchUpdate"), r)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		return s.get(parts[0], r)
+	case len(parts) == 2 && parts[1] == "values:batchUpdate" && r.Method == http.MethodPost:
+		return s.batchUpdateValues(parts[0], r)
+	case len(parts) == 3 && parts[1] == "values" && r.Method == http.MethodGet:
+		return s.getValues(parts[0], parts[2], r)
+	case len(parts) == 3 && parts[1] == "values" && r.Method == http.MethodPut:
+		return s.updateValues(parts[0], parts[2], r)
+	case len(parts) == 3 && parts[1] == "values" && strings.HasSuffix(parts[2], ":append") && r.Method == http.MethodPost:
+		return s.appendValues(parts[0], strings.TrimSuffix(parts[2], ":append"), r)
+	case len(parts) == 3 && parts[1] == "values" && strings.HasSuffix(parts[2], ":clear") && r.Method == http.MethodPost:
+		return s.clearValues(parts[0], strings.TrimSuffix(parts[2], ":clear"))
+	}
+	return nil, errorf(http.StatusNotFound, "unsupported %s %s", r.Method, r.URL.Path)
+}
+
+func (s *Server) spreadsheet(id string) (*sheets.Spreadsheet, error) {
+	spreadsheet, ok := s.spreadsheets[id]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, "Requested entity was not found.")
+	}
+	return spreadsheet, nil
+}
+
+func decode(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errorf(http.StatusBadRequest, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) create(r *http.Request) (interface{}, error) {
+	var req sheets.Spreadsheet
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.created++
+	spreadsheet := &sheets.Spreadsheet{
+		SpreadsheetId: fmt.Sprintf("spreadsheet-%d", s.created),
+		Properties:    req.Properties,
+	}
+	if spreadsheet.Properties == nil {
+		spreadsheet.Properties = &sheets.SpreadsheetProperties{Title: "Untitled spreadsheet"}
+	}
+	spreadsheet.SpreadsheetUrl = fmt.Sprintf("%s/spreadsheets/d/%s/edit", s.URL, spreadsheet.SpreadsheetId)
+	for _, sheet := range req.Sheets {
+		if sheet.Properties == nil {
+			sheet.Properties = &sheets.SheetProperties{}
+		}
+		addSheet(spreadsheet, sheet.Properties)
+	}
+	if len(spreadsheet.Sheets) == 0 {
+		addSheet(spreadsheet, &sheets.SheetProperties{Title: "Sheet1"})
+	}
+	s.spreadsheets[spreadsheet.SpreadsheetId] = spreadsheet
+	return withoutGridData(spreadsheet), nil
+}
+
+func (s *Server) get(id string, r *http.Request) (interface{}, error) {
+	spreadsheet, err := s.spreadsheet(id)
+	if err != nil {
+		return nil, err
+	}
+	if include, _ := strconv.ParseBool(r.URL.Query().Get("includeGridData")); include {
+		return spreadsheet, nil
+	}
+	return withoutGridData(spreadsheet), nil
+}
+
+func (s *Server) updateValues(id, a1 string, r *http.Request) (interface{}, error) {
+	spreadsheet, err := s.spreadsheet(id)
+	if err != nil {
+		return nil, err
+	}
+	var req sheets.ValueRange
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	return writeValues(spreadsheet, a1, &req, r.URL.Query().Get("valueInputOption"))
+}
+
+func (s *Server) getValues(id, a1 string, r *http.Request) (interface{}, error) {
+	spreadsheet, err := s.spreadsheet(id)
+	if err != nil {
+		return nil, err
+	}
+	if option := r.URL.Query().Get("valueRenderOption"); option != "FORMULA" {
+		return nil, errorf(http.StatusBadRequest, "unsupported valueRenderOption %q, the fake only renders FORMULA", option)
+	}
+	sheet, row, column, err := parseRange(spreadsheet, a1)
+	if err != nil {
+		return nil, err
+	}
+	endRow, endColumn, err := parseEnd(spreadsheet, a1)
+	if err != nil {
+		return nil, err
+	}
+	data := grid(sheet)
+	if endRow == 0 || endRow > int64(len(data.RowData)) {
+		endRow = int64(len(data.RowData))
+	}
+
+	resp := &sheets.ValueRange{Range: a1, MajorDimension: "ROWS"}
+	for i := row; i < endRow; i++ {
+		cells := data.RowData[i].Values
+		last := int64(len(cells))
+		if endColumn > 0 && endColumn < last {
+			last = endColumn
+		}
+		var values []interface{}
+		for j := column; j < last; j++ {
+			values = append(values, renderFormula(cells[j].UserEnteredValue))
+		}
+		// trailing empty cells and rows are left out, like the real API
+		for len(values) > 0 && values[len(values)-1] == "" {
+			values = values[:len(values)-1]
+		}
+		resp.Values = append(resp.Values, values)
+	}
+	for len(resp.Values) > 0 && len(resp.Values[len(resp.Values)-1]) == 0 {
+		resp.Values = resp.Values[:len(resp.Values)-1]
+	}
+	return resp, nil
+}
+
+// renderFormula renders a cell value the way values.get does with the
+// FORMULA render option.
+func renderFormula(v *sheets.ExtendedValue) interface{} {
+	switch {
+	case v == nil:
+		return ""
+	case v.FormulaValue != nil:
+		return *v.FormulaValue
+	case v.NumberValue != nil:
+		return *v.NumberValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.StringValue != nil:
+		return *v.StringValue
+	}
+	return ""
+}
+
+func (s *Server) appendValues(id, a1 string, r *http.Request) (interface{}, error) {
+	spreadsheet, err := s.spreadsheet(id)
+	if err != nil {
+		return nil, err
+	}
+	switch option := r.URL.Query().Get("insertDataOption"); option {
+	case "", "OVERWRITE", "INSERT_ROWS":
+	default:
+		return nil, errorf(http.StatusBadRequest, "Invalid insertDataOption: %q", option)
+	}
+	var req sheets.ValueRange
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	sheet, row, column, err := parseRange(spreadsheet, a1)
+	if err != nil {
+		return nil, err
+	}
+	// the values go below the last row with anything in it
+	next := row
+	for i, rowData := range grid(sheet).RowData {
+		for j, c := range rowData.Values {
+			if int64(i) >= row && int64(j) >= column && c.UserEnteredValue != nil {
+				next = int64(i) + 1
+				break
+			}
+		}
+	}
+	row = next
+	start := tracker.SheetRange(sheet.Properties.Title, tracker.Cell(column, row+1))
+	update, err := writeValues(spreadsheet, start, &req, r.URL.Query().Get("valueInputOption"))
+	if err != nil {
+		return nil, err
+	}
+	return &sheets.AppendValuesResponse{SpreadsheetId: id, Updates: update}, nil
+}
+
+func (s *Server) clearValues(id, a1 string) (interface{}, error) {
+	spreadsheet, err := s.spreadsheet(id)
+	if err != nil {
+		return nil, err
+	}
+	sheet, row, column, err := parseRange(spreadsheet, a1)
+	if err != nil {
+		return nil, err
+	}
+	endRow, endColumn, err := parseEnd(spreadsheet, a1)
+	if err != nil {
+		return nil, err
+	}
+	for i, rowData := range grid(sheet).RowData {
+		if int64(i) < row || (endRow > 0 && int64(i) >= endRow) {
+			continue
+		}
+		for j, c := range rowData.Values {
+			if int64(j) >= column && (endColumn == 0 || int64(j) < endColumn) {
+				// formats are kept, like the real API
+				c.UserEnteredValue = nil
+			}
+		}
+	}
+	return &sheets.ClearValuesResponse{SpreadsheetId: id, ClearedRange: a1}, nil
+}
+
+func (s *Server) batchUpdateValues(id string, r *http.Request) (interface{}, error) {
+	spreadsheet, err := s.spreadsheet(id)
+	if err != nil {
+		return nil, err
+	}
+	var req sheets.BatchUpdateValuesRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	// all or nothing, like the real API
+	updated := clone(spreadsheet)
+	resp := &sheets.BatchUpdateValuesResponse{SpreadsheetId: id}
+	for _, data := range req.Data {
+		update, err := writeValues(updated, data.Range, data, req.ValueInputOption)
+		if err != nil {
+			return nil, err
+		}
+		resp.Responses = append(resp.Responses, update)
+		resp.TotalUpdatedRows += update.UpdatedRows
+		resp.TotalUpdatedColumns += update.UpdatedColumns
+		resp.TotalUpdatedCells += update.UpdatedCells
+		resp.TotalUpdatedSheets++
+	}
+	s.spreadsheets[id] = updated
+	return resp, nil
+}
+
+func (s *Server) batchUpdate(id string, r *http.Request) (interface{}, error) {
+	spreadsheet, err := s.spreadsheet(id)
+	if err != nil {
+		return nil, err
+	}
+	var req sheets.BatchUpdateSpreadsheetRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	// all or nothing, like the real API
+	updated := clone(spreadsheet)
+	resp := &sheets.BatchUpdateSpreadsheetResponse{SpreadsheetId: id}
+	for i, request := range req.Requests {
+		reply, err := apply(updated, request)
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "Invalid requests[%d]: %v", i, err)
+		}
+		resp.Replies = append(resp.Replies, reply)
+	}
+	s.spreadsheets[id] = updated
+	return resp, nil
+}
+
+func apply(spreadsheet *sheets.Spreadsheet, request *sheets.Request) (*sheets.Response, error) {
+	switch {
+	case request.AddSheet != nil:
+		properties := request.AddSheet.Properties
+		if properties == nil {
+			properties = &sheets.SheetProperties{}
+		}
+		if properties.Title != "" && sheetByTitle(spreadsheet, properties.Title) != nil {
+			return nil, fmt.Errorf("a sheet with the name %q already exists", properties.Title)
+		}
+		sheet := addSheet(spreadsheet, properties)
+		var added sheets.SheetProperties
+		copyJSON(sheet.Properties, &added)
+		return &sheets.Response{AddSheet: &sheets.AddSheetResponse{Properties: &added}}, nil
+	case request.RepeatCell != nil:
+		return &sheets.Response{}, repeatCell(spreadsheet, request.RepeatCell)
+	case request.UpdateBorders != nil:
+		return &sheets.Response{}, updateBorders(spreadsheet, request.UpdateBorders)
+	case request.AddConditionalFormatRule != nil:
+		return &sheets.Response{}, addConditionalFormatRule(spreadsheet, request.AddConditionalFormatRule)
+	case request.DeleteConditionalFormatRule != nil:
+		return &sheets.Response{}, deleteConditionalFormatRule(spreadsheet, request.DeleteConditionalFormatRule)
+	case request.AddChart != nil:
+		chart, err := addChart(spreadsheet, request.AddChart)
+		if err != nil {
+			return nil, err
+		}
+		return &sheets.Response{AddChart: &sheets.AddChartResponse{Chart: chart}}, nil
+	}
+	return nil, fmt.Errorf("unsupported request")
+}
+
+// addSheet appends a tab, filling in an unused id and a default title.
+func addSheet(spreadsheet *sheets.Spreadsheet, properties *sheets.SheetProperties) *sheets.Sheet {
+	var p sheets.SheetProperties
+	copyJSON(properties, &p)
+	if p.SheetId == 0 && len(spreadsheet.Sheets) > 0 {
+		for _, sheet := range spreadsheet.Sheets {
+			if sheet.Properties.SheetId >= p.SheetId {
+				p.SheetId = sheet.Properties.SheetId + 1
+			}
+		}
+	}
+	if p.Title == "" {
+		p.Title = fmt.Sprintf("Sheet%d", len(spreadsheet.Sheets)+1)
+	}
+	p.Index = int64(len(spreadsheet.Sheets))
+	if p.SheetType == "" {
+		p.SheetType = "GRID"
+	}
+	sheet := &sheets.Sheet{
+		Properties: &p,
+		Data:       []*sheets.GridData{{}},
+	}
+	spreadsheet.Sheets = append(spreadsheet.Sheets, sheet)
+	return sheet
+}
+
+func sheetByTitle(spreadsheet *sheets.Spreadsheet, title string) *sheets.Sheet {
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.Title == title {
+			return sheet
+		}
+	}
+	return nil
+}
+
+func sheetByID(spreadsheet *sheets.Spreadsheet, id int64) (*sheets.Sheet, error) {
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.SheetId == id {
+			return sheet, nil
+		}
+	}
+	return nil, fmt.Errorf("no grid with id: %d", id)
+}
+
+// grid is the single block of data every fake tab keeps, anchored at A1.
+func grid(sheet *sheets.Sheet) *sheets.GridData {
+	if len(sheet.Data) == 0 {
+		sheet.Data = []*sheets.GridData{{}}
+	}
+	return sheet.Data[0]
+}
+
+// cell returns the zero based cell of a tab, growing the grid to reach it.
+func cell(sheet *sheets.Sheet, row, column int64) *sheets.CellData {
+	data := grid(sheet)
+	for int64(len(data.RowData)) <= row {
+		data.RowData = append(data.RowData, &sheets.RowData{})
+	}
+	rowData := data.RowData[row]
+	for int64(len(rowData.Values)) <= column {
+		rowData.Values = append(rowData.Values, &sheets.CellData{})
+	}
+	return rowData.Values[column]
+}
+
+// parseRange resolves an A1 range such as "'My tab'!B2:C3" to a tab and the
+// zero based row and column of its top left cell. Without a tab name the
+// first tab is used, and a bare tab name starts at A1.
+func parseRange(spreadsheet *sheets.Spreadsheet, a1 string) (sheet *sheets.Sheet, row, column int64, err error) {
+	title, ref := "", a1
+	if i := strings.LastIndex(a1, "!"); i >= 0 {
+		title, ref = a1[:i], a1[i+1:]
+	} else if sheetByTitle(spreadsheet, a1) != nil {
+		title, ref = a1, ""
+	}
+	if strings.HasPrefix(title, "'") && strings.HasSuffix(title, "'") && len(title) > 1 {
+		title = strings.ReplaceAll(title[1:len(title)-1], "''", "'")
+	}
+
+	switch {
+	case title != "":
+		sheet = sheetByTitle(spreadsheet, title)
+	case len(spreadsheet.Sheets) > 0:
+		sheet = spreadsheet.Sheets[0]
+	}
+	if sheet == nil {
+		return nil, 0, 0, errorf(http.StatusBadRequest, "Unable to parse range: %s", a1)
+	}
+	if ref == "" {
+		return sheet, 0, 0, nil
+	}
+	if i := strings.Index(ref, ":"); i >= 0 {
+		ref = ref[:i]
+	}
+	column, row, err = tracker.ParseCell(ref)
+	if err != nil {
+		return nil, 0, 0, errorf(http.StatusBadRequest, "Unable to parse range: %s", a1)
+	}
+	return sheet, row - 1, column, nil
+}
+
+// parseEnd resolves the end of an A1 range such as "B2:C" to zero based, end
+// exclusive row and column indexes; 0 means the range reaches the edge of the
+// data. A single cell ends right after itself.
+func parseEnd(spreadsheet *sheets.Spreadsheet, a1 string) (endRow, endColumn int64, err error) {
+	ref := a1
+	if i := strings.LastIndex(a1, "!"); i >= 0 {
+		ref = a1[i+1:]
+	} else if sheetByTitle(spreadsheet, a1) != nil {
+		ref = ""
+	}
+	if ref == "" {
+		return 0, 0, nil
+	}
+	if i := strings.Index(ref, ":"); i >= 0 {
+		ref = ref[i+1:]
+	}
+	letters := strings.TrimRight(ref, "0123456789")
+	if letters != "" {
+		column, err := tracker.ColumnIndex(letters)
+		if err != nil {
+			return 0, 0, errorf(http.StatusBadRequest, "Unable to parse range: %s", a1)
+		}
+		endColumn = column + 1
+	}
+	if digits := ref[len(letters):]; digits != "" {
+		if endRow, err = strconv.ParseInt(digits, 10, 64); err != nil || endRow < 1 {
+			return 0, 0, errorf(http.StatusBadRequest, "Unable to parse range: %s", a1)
+		}
+	}
+	return endRow, endColumn, nil
+}
+
+// writeValues stores a block of values, interpreting them as typed into the
+// UI for USER_ENTERED and as literal values for RAW.
+func writeValues(spreadsheet *sheets.Spreadsheet, a1 string, vr *sheets.ValueRange, valueInputOption string) (*sheets.UpdateValuesResponse, error) {
+	if valueInputOption != "RAW" && valueInputOption != "USER_ENTERED" {
+		return nil, errorf(http.StatusBadRequest, "Invalid valueInputOption: %q", valueInputOption)
+	}
+	if vr.MajorDimension != "" && vr.MajorDimension != "ROWS" {
+		return nil, errorf(http.StatusBadRequest, "unsupported majorDimension %s", vr.MajorDimension)
+	}
+	sheet, row, column, err := parseRange(spreadsheet, a1)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &sheets.UpdateValuesResponse{SpreadsheetId: spreadsheet.SpreadsheetId}
+	for i, values := range vr.Values {
+		for j, v := range values {
+			if v == nil {
+				// null leaves the cell untouched
+				continue
+			}
+			value, err := extendedValue(v, valueInputOption)
+			if err != nil {
+				return nil, errorf(http.StatusBadRequest, "%s: %v", tracker.Cell(column+int64(j), row+int64(i)+1), err)
+			}
+			cell(sheet, row+int64(i), column+int64(j)).UserEnteredValue = value
+			resp.UpdatedCells++
+		}
+		if int64(len(values)) > resp.UpdatedColumns {
+			resp.UpdatedColumns = int64(len(values))
+		}
+	}
+	resp.UpdatedRows = int64(len(vr.Values))
+	if resp.UpdatedRows > 0 && resp.UpdatedColumns > 0 {
+		resp.UpdatedRange = tracker.SheetRange(sheet.Properties.Title, fmt.Sprintf("%s:%s",
+			tracker.Cell(column, row+1),
+			tracker.Cell(column+resp.UpdatedColumns-1, row+resp.UpdatedRows),
+		))
+	}
+	return resp, nil
+}
+
+func extendedValue(v interface{}, valueInputOption string) (*sheets.ExtendedValue, error) {
+	switch v := v.(type) {
+	case float64:
+		return &sheets.ExtendedValue{NumberValue: &v}, nil
+	case bool:
+		return &sheets.ExtendedValue{BoolValue: &v}, nil
+	case string:
+		if valueInputOption == "USER_ENTERED" {
+			if strings.HasPrefix(v, "=") {
+				return &sheets.ExtendedValue{FormulaValue: &v}, nil
+			}
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				return &sheets.ExtendedValue{NumberValue: &n}, nil
+			}
+			if b, err := strconv.ParseBool(v); err == nil && strings.EqualFold(v, strconv.FormatBool(b)) {
+				return &sheets.ExtendedValue{BoolValue: &b}, nil
+			}
+		}
+		return &sheets.ExtendedValue{StringValue: &v}, nil
+	}
+	return nil, fmt.Errorf("unsupported value %v", v)
+}
+
+// bounds resolves a grid range to zero based, end exclusive indexes. Unset
+// end indexes reach the edge of the written data.
+func bounds(sheet *sheets.Sheet, r *sheets.GridRange) (startRow, endRow, startColumn, endColumn int64) {
+	data := grid(sheet)
+	startRow, endRow = r.StartRowIndex, r.EndRowIndex
+	startColumn, endColumn = r.StartColumnIndex, r.EndColumnIndex
+	if endRow == 0 {
+		endRow = int64(len(data.RowData))
+	}
+	if endColumn == 0 {
+		for _, rowData := range data.RowData {
+			if int64(len(rowData.Values)) > endColumn {
+				endColumn = int64(len(rowData.Values))
+			}
+		}
+	}
+	return startRow, endRow, startColumn, endColumn
+}
+
+func repeatCell(spreadsheet *sheets.Spreadsheet, req *sheets.RepeatCellRequest) error {
+	if req.Range == nil || req.Cell == nil {
+		return fmt.Errorf("range and cell are required")
+	}
+	fields, err := parseFields(req.Fields)
+	if err != nil {
+		return err
+	}
+	sheet, err := sheetByID(spreadsheet, req.Range.SheetId)
+	if err != nil {
+		return err
+	}
+	startRow, endRow, startColumn, endColumn := bounds(sheet, req.Range)
+	for row := startRow; row < endRow; row++ {
+		for column := startColumn; column < endColumn; column++ {
+			c := cell(sheet, row, column)
+			merge(c, req.Cell, fields)
+		}
+	}
+	return nil
+}
+
+func updateBorders(spreadsheet *sheets.Spreadsheet, req *sheets.UpdateBordersRequest) error {
+	if req.Range == nil {
+		return fmt.Errorf("range is required")
+	}
+	sheet, err := sheetByID(spreadsheet, req.Range.SheetId)
+	if err != nil {
+		return err
+	}
+	startRow, endRow, startColumn, endColumn := bounds(sheet, req.Range)
+	for row := startRow; row < endRow; row++ {
+		for column := startColumn; column < endColumn; column++ {
+			c := cell(sheet, row, column)
+			if c.UserEnteredFormat == nil {
+				c.UserEnteredFormat = &sheets.CellFormat{}
+			}
+			if c.UserEnteredFormat.Borders == nil {
+				c.UserEnteredFormat.Borders = &sheets.Borders{}
+			}
+			borders := c.UserEnteredFormat.Borders
+			set := func(edge **sheets.Border, outer bool, outerBorder, innerBorder *sheets.Border) {
+				border := innerBorder
+				if outer {
+					border = outerBorder
+				}
+				// unset borders are left as they were
+				if border != nil {
+					*edge = border
+				}
+			}
+			set(&borders.Top, row == startRow, req.Top, req.InnerHorizontal)
+			set(&borders.Bottom, row == endRow-1, req.Bottom, req.InnerHorizontal)
+			set(&borders.Left, column == startColumn, req.Left, req.InnerVertical)
+			set(&borders.Right, column == endColumn-1, req.Right, req.InnerVertical)
+		}
+	}
+	return nil
+}
+
+func addConditionalFormatRule(spreadsheet *sheets.Spreadsheet, req *sheets.AddConditionalFormatRuleRequest) error {
+	if req.Rule == nil || len(req.Rule.Ranges) == 0 {
+		return fmt.Errorf("rule with at least one range is required")
+	}
+	if (req.Rule.BooleanRule == nil) == (req.Rule.GradientRule == nil) {
+		return fmt.Errorf("rule needs exactly one of booleanRule or gradientRule")
+	}
+	sheet, err := sheetByID(spreadsheet, req.Rule.Ranges[0].SheetId)
+	if err != nil {
+		return err
+	}
+	for _, r := range req.Rule.Ranges[1:] {
+		if r.SheetId != sheet.Properties.SheetId {
+			return fmt.Errorf("all ranges of a rule must be on the same sheet")
+		}
+	}
+	index := req.Index
+	if index < 0 || index > int64(len(sheet.ConditionalFormats)) {
+		index = int64(len(sheet.ConditionalFormats))
+	}
+	rules := append([]*sheets.ConditionalFormatRule{}, sheet.ConditionalFormats[:index]...)
+	rules = append(rules, req.Rule)
+	sheet.ConditionalFormats = append(rules, sheet.ConditionalFormats[index:]...)
+	return nil
+}
+
+func deleteConditionalFormatRule(spreadsheet *sheets.Spreadsheet, req *sheets.DeleteConditionalFormatRuleRequest) error {
+	sheet, err := sheetByID(spreadsheet, req.SheetId)
+	if err != nil {
+		return err
+	}
+	if req.Index < 0 || req.Index >= int64(len(sheet.ConditionalFormats)) {
+		return fmt.Errorf("no conditional format on sheet %d at index %d", req.SheetId, req.Index)
+	}
+	sheet.ConditionalFormats = append(sheet.ConditionalFormats[:req.Index], sheet.ConditionalFormats[req.Index+1:]...)
+	return nil
+}
+
+func addChart(spreadsheet *sheets.Spreadsheet, req *sheets.AddChartRequest) (*sheets.EmbeddedChart, error) {
+	if req.Chart == nil || req.Chart.Spec == nil || req.Chart.Spec.BasicChart == nil {
+		return nil, fmt.Errorf("only basic charts are supported")
+	}
+	spec := req.Chart.Spec.BasicChart
+	if len(spec.Series) == 0 {
+		return nil, fmt.Errorf("a chart needs at least one series")
+	}
+	var sources []*sheets.ChartData
+	for _, domain := range spec.Domains {
+		sources = append(sources, domain.Domain)
+	}
+	for _, series := range spec.Series {
+		sources = append(sources, series.Series)
+	}
+	for _, data := range sources {
+		if data == nil || data.SourceRange == nil || len(data.SourceRange.Sources) == 0 {
+			return nil, fmt.Errorf("chart data needs a source range")
+		}
+		for _, r := range data.SourceRange.Sources {
+			if _, err := sheetByID(spreadsheet, r.SheetId); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// charts go on the tab they are anchored to, or a tab of their own
+	var sheet *sheets.Sheet
+	position := req.Chart.Position
+	switch {
+	case position != nil && position.OverlayPosition != nil && position.OverlayPosition.AnchorCell != nil:
+		var err error
+		if sheet, err = sheetByID(spreadsheet, position.OverlayPosition.AnchorCell.SheetId); err != nil {
+			return nil, err
+		}
+	case position != nil && position.NewSheet:
+		sheet = addSheet(spreadsheet, &sheets.SheetProperties{})
+	default:
+		return nil, fmt.Errorf("only overlay and new sheet chart positions are supported")
+	}
+
+	var chart sheets.EmbeddedChart
+	copyJSON(req.Chart, &chart)
+	for _, other := range spreadsheet.Sheets {
+		for _, c := range other.Charts {
+			if c.ChartId >= chart.ChartId {
+				chart.ChartId = c.ChartId + 1
+			}
+		}
+	}
+	sheet.Charts = append(sheet.Charts, &chart)
+	var added sheets.EmbeddedChart
+	copyJSON(&chart, &added)
+	return &added, nil
+}
+
+// parseFields splits a field mask such as "userEnteredFormat.numberFormat"
+// or "userEnteredFormat(numberFormat,textFormat)" into cell field paths.
+func parseFields(mask string) ([][]string, error) {
+	var fields [][]string
+	for _, field := range splitFields(mask) {
+		field = strings.TrimSpace(field)
+		if i := strings.Index(field, "("); i >= 0 && strings.HasSuffix(field, ")") {
+			sub, err := parseFields(field[i+1 : len(field)-1])
+			if err != nil {
+				return nil, err
+			}
+			for _, path := range sub {
+				fields = append(fields, append([]string{field[:i]}, path...))
+			}
+			continue
+		}
+		switch path := strings.Split(field, "."); path[0] {
+		case "*":
+			fields = append(fields, []string{"userEnteredValue"}, []string{"userEnteredFormat"})
+		case "userEnteredValue", "userEnteredFormat", "note":
+			fields = append(fields, path)
+		default:
+			return nil, fmt.Errorf("unsupported field %q", field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("fields is required")
+	}
+	return fields, nil
+}
+
+// splitFields splits on the commas that aren't inside parentheses.
+func splitFields(mask string) []string {
+	var fields []string
+	depth, start := 0, 0
+	for i, r := range mask {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				fields = append(fields, mask[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(mask) {
+		fields = append(fields, mask[start:])
+	}
+	return fields
+}
+
+// merge copies the masked fields of src into dst, clearing the ones src
+// leaves unset.
+func merge(dst, src *sheets.CellData, fields [][]string) {
+	var to, from map[string]interface{}
+	copyJSON(dst, &to)
+	copyJSON(src, &from)
+	if to == nil {
+		to = map[string]interface{}{}
+	}
+	for _, path := range fields {
+		setPath(to, path, lookupPath(from, path))
+	}
+	*dst = sheets.CellData{}
+	copyJSON(to, dst)
+}
+
+func lookupPath(m map[string]interface{}, path []string) interface{} {
+	var v interface{} = m
+	for _, key := range path {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = obj[key]
+	}
+	return v
+}
+
+func setPath(m map[string]interface{}, path []string, v interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[key] = next
+		}
+		m = next
+	}
+	if v == nil {
+		delete(m, path[len(path)-1])
+		return
+	}
+	m[path[len(path)-1]] = v
+}
+
+func withoutGridData(spreadsheet *sheets.Spreadsheet) *sheets.Spreadsheet {
+	stripped := clone(spreadsheet)
+	for _, sheet := range stripped.Sheets {
+		sheet.Data = nil
+	}
+	return stripped
+}
+
+func clone(spreadsheet *sheets.Spreadsheet) *sheets.Spreadsheet {
+	var c sheets.Spreadsheet
+	copyJSON(spreadsheet, &c)
+	return &c
+}
+
+// copyJSON deep copies between values by round tripping through json.
+func copyJSON(from, to interface{}) {
+	b, err := json.Marshal(from)
+	if err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal(b, to); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,146 @@
+package sheetstest_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/igaskin/crypto-tracker/tracker/sheetstest"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestRejectsUnsupportedRequests(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI")
+	service, err := server.Service(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.Spreadsheets.BatchUpdate("sheet", &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{
+			{AddSheet: &sheets.AddSheetRequest{Properties: &sheets.SheetProperties{Title: "New"}}},
+			{DeleteSheet: &sheets.DeleteSheetRequest{SheetId: 0}},
+		},
+	}).Do()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	// batch updates are all or nothing
+	if got := len(server.Spreadsheet("sheet").Sheets); got != 1 {
+		t.Errorf("spreadsheet has %d sheets, want 1", got)
+	}
+}
+
+func TestBatchUpdateValues(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI", "It's mine")
+	service, err := server.Service(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := service.Spreadsheets.Values.BatchUpdate("sheet", &sheets.BatchUpdateValuesRequest{
+		ValueInputOption: "RAW",
+		Data: []*sheets.ValueRange{
+			{Range: "ROI!B2", Values: [][]interface{}{{"=1+1", 2.5}}},
+			{Range: tracker.SheetRange("It's mine", "A1"), Values: [][]interface{}{{true}}},
+		},
+	}).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TotalUpdatedCells != 3 {
+		t.Errorf("updated %d cells, want 3", resp.TotalUpdatedCells)
+	}
+	if got, want := server.Grid("sheet", "ROI"), [][]string{{}, {"", "=1+1", "2.5"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("grid = %q, want %q", got, want)
+	}
+	// RAW keeps formulas as text
+	if c := server.Cell("sheet", "ROI!B2"); c.UserEnteredValue.StringValue == nil {
+		t.Errorf("B2 = %+v, want a string", c.UserEnteredValue)
+	}
+	if got := server.Grid("sheet", "It's mine"); !reflect.DeepEqual(got, [][]string{{"TRUE"}}) {
+		t.Errorf("grid = %q", got)
+	}
+}
+
+func TestGetAndAppendValues(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "Log")
+	service, err := server.Service(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, values := range [][][]interface{}{
+		{{"name", "count"}},
+		{{"a", 1}, {"b", "=B2+1"}},
+	} {
+		_, err := service.Spreadsheets.Values.Append("sheet", "Log!A1", &sheets.ValueRange{Values: values}).
+			ValueInputOption("USER_ENTERED").Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := service.Spreadsheets.Values.Get("sheet", "Log!B2:C").ValueRenderOption("FORMULA").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]interface{}{{1.0}, {"=B2+1"}}; !reflect.DeepEqual(resp.Values, want) {
+		t.Errorf("values = %v, want %v", resp.Values, want)
+	}
+
+	// the fake can't evaluate formulas
+	if _, err := service.Spreadsheets.Values.Get("sheet", "Log!A1:B").Do(); err == nil {
+		t.Error("expected formatted values to be rejected")
+	}
+}
+
+func TestDeleteConditionalFormatRule(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI")
+	service, err := server.Service(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheetID := server.Spreadsheet("sheet").Sheets[0].Properties.SheetId
+	rule := func(condition string) *sheets.Request {
+		return &sheets.Request{AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
+			Index: 0,
+			Rule: &sheets.ConditionalFormatRule{
+				Ranges:      []*sheets.GridRange{{SheetId: sheetID}},
+				BooleanRule: &sheets.BooleanRule{Condition: &sheets.BooleanCondition{Type: condition}},
+			},
+		}}
+	}
+	_, err = service.Spreadsheets.BatchUpdate("sheet", &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{
+			rule("BLANK"),
+			rule("NOT_BLANK"),
+			{DeleteConditionalFormatRule: &sheets.DeleteConditionalFormatRuleRequest{SheetId: sheetID, Index: 1}},
+		},
+	}).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := server.Spreadsheet("sheet").Sheets[0].ConditionalFormats
+	if len(rules) != 1 || rules[0].BooleanRule.Condition.Type != "NOT_BLANK" {
+		t.Errorf("rules = %+v, want the one added last", rules)
+	}
+
+	_, err = service.Spreadsheets.BatchUpdate("sheet", &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{
+			{DeleteConditionalFormatRule: &sheets.DeleteConditionalFormatRuleRequest{SheetId: sheetID, Index: 1}},
+		},
+	}).Do()
+	if err == nil {
+		t.Error("expected deleting a missing rule to fail")
+	}
+}
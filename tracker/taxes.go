@@ -0,0 +1,234 @@
+package tracker
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaxLabel tells a tax service what kind of income, cost or transfer an entry
+// is. Each service names the labels its own way.
+type TaxLabel string
+
+const (
+	// TransferLabel marks funds moved between the user's own wallets, which
+	// tax services expect to be left unlabelled
+	TransferLabel TaxLabel = "transfer"
+	StakingLabel  TaxLabel = "staking"
+	InterestLabel TaxLabel = "interest"
+	CashbackLabel TaxLabel = "cashback"
+	RewardLabel   TaxLabel = "reward"
+	FeeLabel      TaxLabel = "fee"
+)
+
+// internalTransfer labels moves between the wallets of the app.
+const internalTransfer TaxLabel = "internal"
+
+// TaxEntry is a transaction the way tax services import it: what was sent
+// and received, the fee and what it was worth.
+type TaxEntry struct {
+	Timestamp        time.Time
+	SentAmount       float64
+	SentCurrency     string
+	ReceivedAmount   float64
+	ReceivedCurrency string
+	FeeAmount        float64
+	FeeCurrency      string
+	NetWorthAmount   float64
+	NetWorthCurrency string
+	Label            TaxLabel
+	Description      string
+	TxHash           string
+}
+
+// taxLabels labels the transaction kinds that aren't trades. Moves between
+// the wallets of the app, such as into Crypto Earn, aren't reported.
+var taxLabels = map[string]TaxLabel{
+	"crypto_deposit":                TransferLabel,
+	"crypto_withdrawal":             TransferLabel,
+	ExchangeDepositKind:             TransferLabel,
+	ExchangeWithdrawalKind:          TransferLabel,
+	ExchangeTransferKind:            TransferLabel,
+	ExchangeFeeKind:                 FeeLabel,
+	"crypto_earn_program_created":   internalTransfer,
+	"crypto_earn_program_withdrawn": internalTransfer,
+	"supercharger_deposit":          internalTransfer,
+	"supercharger_withdrawal":       internalTransfer,
+	"lockup_lock":                   internalTransfer,
+	"lockup_unlock":                 internalTransfer,
+}
+
+// incomeLabels labels every income source.
+var incomeLabels = map[string]TaxLabel{
+	EarnIncome:     InterestLabel,
+	StakingRewards: StakingLabel,
+	CardRewards:    CashbackLabel,
+	Referrals:      RewardLabel,
+}
+
+// app transactions exchanging CRO with the fiat they're valued in, without
+// listing the fiat as a currency
+const (
+	cardPurchaseKind = "crypto_purchase"
+	cardTopUpKind    = "card_top_up"
+)
+
+// TaxEntries converts transactions to tax entries, oldest first.
+func TaxEntries(transactions []Transaction) []TaxEntry {
+	var entries []TaxEntry
+	for _, t := range transactions {
+		label := taxLabels[t.Kind]
+		if source, income := incomeKinds[t.Kind]; income {
+			label = incomeLabels[source]
+		}
+		if label == internalTransfer {
+			continue
+		}
+		e := TaxEntry{
+			Timestamp:   t.Timestamp,
+			Label:       label,
+			Description: t.Description,
+		}
+		if t.NativeCurrency != "" && t.NativeAmount != 0 {
+			e.NetWorthAmount, e.NetWorthCurrency = math.Abs(t.NativeAmount), t.NativeCurrency
+		}
+		switch {
+		case label == FeeLabel:
+			e.FeeAmount, e.FeeCurrency = math.Abs(t.Amount), t.Currency
+		case t.ToCurrency != "":
+			e.SentAmount, e.SentCurrency = math.Abs(t.Amount), t.Currency
+			e.ReceivedAmount, e.ReceivedCurrency = math.Abs(t.ToAmount), t.ToCurrency
+		case t.Kind == cardPurchaseKind && e.NetWorthCurrency != "":
+			e.SentAmount, e.SentCurrency = e.NetWorthAmount, e.NetWorthCurrency
+			e.ReceivedAmount, e.ReceivedCurrency = math.Abs(t.Amount), t.Currency
+		case t.Kind == cardTopUpKind && e.NetWorthCurrency != "":
+			e.SentAmount, e.SentCurrency = math.Abs(t.Amount), t.Currency
+			e.ReceivedAmount, e.ReceivedCurrency = e.NetWorthAmount, e.NetWorthCurrency
+		case t.Amount < 0:
+			e.SentAmount, e.SentCurrency = -t.Amount, t.Currency
+		default:
+			e.ReceivedAmount, e.ReceivedCurrency = t.Amount, t.Currency
+		}
+		entries = append(entries, e)
+	}
+	SortTaxEntries(entries)
+	return entries
+}
+
+// SortTaxEntries orders entries oldest first.
+func SortTaxEntries(entries []TaxEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+}
+
+// TaxFormat is the universal csv import of a tax service.
+type TaxFormat struct {
+	Name   string
+	header []string
+	row    func(e TaxEntry) []string
+}
+
+var (
+	// Koinly is Koinly's universal import format
+	Koinly = TaxFormat{
+		Name: "koinly",
+		header: []string{"Date", "Sent Amount", "Sent Currency", "Received Amount", "Received Currency",
+			"Fee Amount", "Fee Currency", "Net Worth Amount", "Net Worth Currency", "Label", "Description", "TxHash"},
+		row: koinlyRow,
+	}
+	// CoinTracker is CoinTracker's universal import format
+	CoinTracker = TaxFormat{
+		Name: "cointracker",
+		header: []string{"Date", "Received Quantity", "Received Currency", "Sent Quantity", "Sent Currency",
+			"Fee Amount", "Fee Currency", "Tag"},
+		row: coinTrackerRow,
+	}
+)
+
+// TaxFormats are the formats entries can be written in.
+var TaxFormats = []TaxFormat{Koinly, CoinTracker}
+
+// TaxFormatNamed finds a tax format by name.
+func TaxFormatNamed(name string) (TaxFormat, error) {
+	var names []string
+	for _, f := range TaxFormats {
+		if strings.EqualFold(f.Name, name) {
+			return f, nil
+		}
+		names = append(names, f.Name)
+	}
+	return TaxFormat{}, fmt.Errorf("unknown format %q, expected %s", name, strings.Join(names, " or "))
+}
+
+// Write writes the header and a row per entry.
+func (f TaxFormat) Write(w io.Writer, entries []TaxEntry) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(f.header); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := out.Write(f.row(e)); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// koinlyLabels are Koinly's names for the labels.
+var koinlyLabels = map[TaxLabel]string{
+	StakingLabel:  "staking",
+	InterestLabel: "loan interest",
+	CashbackLabel: "cashback",
+	RewardLabel:   "reward",
+	FeeLabel:      "cost",
+}
+
+// Koinly records a fee charged on its own as the fee sent, labelled a cost.
+func koinlyRow(e TaxEntry) []string {
+	if e.Label == FeeLabel && e.SentCurrency == "" {
+		e.SentAmount, e.SentCurrency = e.FeeAmount, e.FeeCurrency
+		e.FeeAmount, e.FeeCurrency = 0, ""
+	}
+	return []string{
+		e.Timestamp.UTC().Format("2006-01-02 15:04:05 UTC"),
+		taxAmount(e.SentAmount), e.SentCurrency,
+		taxAmount(e.ReceivedAmount), e.ReceivedCurrency,
+		taxAmount(e.FeeAmount), e.FeeCurrency,
+		taxAmount(e.NetWorthAmount), e.NetWorthCurrency,
+		koinlyLabels[e.Label], e.Description, e.TxHash,
+	}
+}
+
+// coinTrackerTags are CoinTracker's names for the labels. Fees charged on
+// their own only fill in the fee columns.
+var coinTrackerTags = map[TaxLabel]string{
+	StakingLabel:  "staked",
+	InterestLabel: "interest",
+	CashbackLabel: "income",
+	RewardLabel:   "income",
+}
+
+func coinTrackerRow(e TaxEntry) []string {
+	return []string{
+		e.Timestamp.UTC().Format("01/02/2006 15:04:05"),
+		taxAmount(e.ReceivedAmount), e.ReceivedCurrency,
+		taxAmount(e.SentAmount), e.SentCurrency,
+		taxAmount(e.FeeAmount), e.FeeCurrency,
+		coinTrackerTags[e.Label],
+	}
+}
+
+// taxAmount formats an amount in full, empty when there's none.
+func taxAmount(amount float64) string {
+	if amount == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
@@ -0,0 +1,57 @@
+package tracker_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+)
+
+// TestTaxFormatsGolden writes an export of every app transaction kind and
+// the exchange trades in each tax format and compares them with
+// testdata/taxes/<format>.csv. Run with -update after an intentional change.
+func TestTaxFormatsGolden(t *testing.T) {
+	var transactions []tracker.Transaction
+	for _, export := range []string{"usd_all_types.csv", "exchange_trades.csv"} {
+		parsed, err := tracker.ReadTransactionsFile(filepath.Join("testdata", "exports", export))
+		if err != nil {
+			t.Fatal(err)
+		}
+		transactions = append(transactions, parsed...)
+	}
+	entries := tracker.TaxEntries(transactions)
+	for _, format := range tracker.TaxFormats {
+		format := format
+		t.Run(format.Name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := format.Write(&got, entries); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "taxes", format.Name+".csv")
+			if *update {
+				if err := ioutil.WriteFile(golden, got.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("%s doesn't match %s\ngot:\n%s\nwant:\n%s", format.Name, golden, got.Bytes(), want)
+			}
+		})
+	}
+}
+
+func TestTaxFormatNamed(t *testing.T) {
+	if format, err := tracker.TaxFormatNamed("CoinTracker"); err != nil || format.Name != "cointracker" {
+		t.Errorf("TaxFormatNamed(CoinTracker) = %s, %v", format.Name, err)
+	}
+	if _, err := tracker.TaxFormatNamed("turbotax"); err == nil {
+		t.Error("found a format for turbotax")
+	}
+}
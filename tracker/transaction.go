@@ -0,0 +1,340 @@
+package tracker
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transaction is a single row of a Crypto.com App transactions export.
+type Transaction struct {
+	Timestamp       time.Time
+	Description     string
+	Currency        string
+	Amount          float64
+	ToCurrency      string
+	ToAmount        float64
+	NativeCurrency  string
+	NativeAmount    float64
+	NativeAmountUSD float64
+	Kind            string
+}
+
+// CRODelta is the change in CRO held by the app wallet caused by the transaction.
+func (t Transaction) CRODelta() float64 {
+	var delta float64
+	if t.Currency == "CRO" {
+		delta += t.Amount
+	}
+	if t.ToCurrency == "CRO" {
+		delta += t.ToAmount
+	}
+	return delta
+}
+
+// Fingerprint identifies a transaction across exports, so overlapping exports
+// can be merged without importing a row twice. The USD amount is left out
+// since older exports don't have it.
+func (t Transaction) Fingerprint() string {
+	h := sha256.New()
+	for _, field := range []string{
+		t.Timestamp.UTC().Format(time.RFC3339Nano),
+		t.Description,
+		t.Currency,
+		strconv.FormatFloat(t.Amount, 'g', -1, 64),
+		t.ToCurrency,
+		strconv.FormatFloat(t.ToAmount, 'g', -1, 64),
+		t.NativeCurrency,
+		strconv.FormatFloat(t.NativeAmount, 'g', -1, 64),
+		t.Kind,
+	} {
+		io.WriteString(h, field)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// twinKeys keys every transaction of an export by its fingerprint. Identical
+// rows within one export, such as two equal cashbacks in the same second, are
+// told apart by their position among their twins.
+func twinKeys(transactions []Transaction) []string {
+	twins := map[string]int{}
+	keys := make([]string, len(transactions))
+	for i, transaction := range transactions {
+		fingerprint := transaction.Fingerprint()
+		keys[i] = fmt.Sprintf("%s#%d", fingerprint, twins[fingerprint])
+		twins[fingerprint]++
+	}
+	return keys
+}
+
+// MergeTransactions merges the transactions of overlapping exports, oldest
+// first, dropping the ones an earlier export already has. skipped counts the
+// dropped duplicates.
+func MergeTransactions(exports ...[]Transaction) (merged []Transaction, skipped int) {
+	seen := map[string]bool{}
+	for _, transactions := range exports {
+		for i, key := range twinKeys(transactions) {
+			if seen[key] {
+				skipped++
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, transactions[i])
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+	return merged, skipped
+}
+
+// TimestampLayout is the format of timestamps in Crypto.com App exports.
+const TimestampLayout = "2006-01-02 15:04:05"
+
+// ParseError reports a row of an export that couldn't be parsed.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// columnNames are the header names of the columns of an export, in the
+// order NewTransaction expects them. Older app versions call the first column
+// "Timestamp" and leave out "Native Amount (in USD)"; newer ones append
+// columns such as "Transaction Hash", which are ignored.
+var columnNames = [...]string{
+	"Timestamp (UTC)",
+	"Transaction Description",
+	"Currency",
+	"Amount",
+	"To Currency",
+	"To Amount",
+	"Native Currency",
+	"Native Amount",
+	"Native Amount (in USD)",
+	"Transaction Kind",
+}
+
+const nativeAmountUSDColumn = 8
+
+// layout is the index of every column of columnNames in a record, -1 when the
+// export doesn't have it.
+type layout [len(columnNames)]int
+
+var defaultLayout = layout{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+// appFormat is a Crypto.com App transactions export.
+type appFormat struct{}
+
+func (appFormat) Name() string {
+	return "crypto.com app"
+}
+
+// Detect recognizes the header by its first column, so a header missing a
+// column is reported rather than parsed as a transaction.
+func (appFormat) Detect(header []string) bool {
+	return len(header) > 0 && strings.HasPrefix(strings.TrimPrefix(header[0], "\ufeff"), "Timestamp")
+}
+
+func (appFormat) Parser(header []string) (Parser, error) {
+	return parseHeader(header)
+}
+
+// parseHeader locates the columns of an export from its header row.
+func parseHeader(header []string) (layout, error) {
+	var l layout
+	for i := range l {
+		l[i] = -1
+	}
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if strings.EqualFold(name, "Timestamp") {
+			name = columnNames[0]
+		}
+		for j, want := range columnNames {
+			if strings.EqualFold(name, want) {
+				l[j] = i
+			}
+		}
+	}
+	for j, index := range l {
+		if index < 0 && j != nativeAmountUSDColumn {
+			return layout{}, fmt.Errorf("missing column %q", columnNames[j])
+		}
+	}
+	return l, nil
+}
+
+// ParseTransactions parses every transaction from a csv export of any of the
+// registered formats, recognized by its header row. Exports may start with a
+// few lines of preamble before the header. A Crypto.com App export is also
+// parsed without a header.
+func ParseTransactions(r io.Reader) ([]Transaction, error) {
+	reader := csv.NewReader(r)
+	// rows are checked against the header below, not against each other
+	reader.FieldsPerRecord = -1
+	var parser Parser
+	var firstErr error
+	var transactions []Transaction
+	line := 0
+	for {
+		line++
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &ParseError{Line: line, Err: err}
+		}
+		if parser == nil {
+			if format, ok := DetectFormat(record); ok {
+				if parser, err = format.Parser(record); err != nil {
+					return nil, &ParseError{Line: line, Err: err}
+				}
+				continue
+			}
+			if line == 1 {
+				transaction, err := defaultLayout.transaction(record)
+				if err == nil {
+					parser = defaultLayout
+					transactions = append(transactions, transaction)
+					continue
+				}
+				firstErr = err
+			}
+			if line < maxPreambleLines {
+				continue
+			}
+			return nil, &ParseError{Line: 1, Err: unrecognizedExport(firstErr)}
+		}
+		parsed, err := parser.Parse(record)
+		if err != nil {
+			return nil, &ParseError{Line: line, Err: err}
+		}
+		transactions = append(transactions, parsed...)
+	}
+	if parser == nil {
+		if firstErr != nil {
+			return nil, &ParseError{Line: 1, Err: unrecognizedExport(firstErr)}
+		}
+		return nil, nil
+	}
+	pending, err := parser.Flush()
+	if err != nil {
+		// the export ended before the parser could finish
+		return nil, &ParseError{Line: line - 1, Err: err}
+	}
+	return append(transactions, pending...), nil
+}
+
+// maxPreambleLines is how far into an export ParseTransactions looks for a
+// header.
+const maxPreambleLines = 10
+
+func unrecognizedExport(err error) error {
+	return fmt.Errorf("no header of a known export (%s) and not a Crypto.com App transaction: %v",
+		strings.Join(FormatNames(), ", "), err)
+}
+
+// ReadTransactionsFile parses a csv export from disk.
+func ReadTransactionsFile(path string) ([]Transaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	transactions, err := ParseTransactions(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return transactions, nil
+}
+
+// ReadTransactionsFiles parses every export and merges them with
+// MergeTransactions.
+func ReadTransactionsFiles(paths ...string) (merged []Transaction, skipped int, err error) {
+	exports := make([][]Transaction, len(paths))
+	for i, path := range paths {
+		if exports[i], err = ReadTransactionsFile(path); err != nil {
+			return nil, 0, err
+		}
+	}
+	merged, skipped = MergeTransactions(exports...)
+	return merged, skipped, nil
+}
+
+func (l layout) Parse(record []string) ([]Transaction, error) {
+	transaction, err := l.transaction(record)
+	if err != nil {
+		return nil, err
+	}
+	return []Transaction{transaction}, nil
+}
+
+func (l layout) Flush() ([]Transaction, error) {
+	return nil, nil
+}
+
+// NewTransaction parses a single csv record of a Crypto.com App export, with
+// the columns in their usual order.
+func NewTransaction(record []string) (Transaction, error) {
+	return defaultLayout.transaction(record)
+}
+
+func (l layout) transaction(record []string) (Transaction, error) {
+	var want int
+	for _, index := range l {
+		if index >= want {
+			want = index + 1
+		}
+	}
+	if len(record) < want {
+		return Transaction{}, fmt.Errorf("expected %d columns, got %d", want, len(record))
+	}
+	column := func(i int) string {
+		if l[i] < 0 {
+			return ""
+		}
+		return strings.TrimSpace(record[l[i]])
+	}
+	timestamp, err := time.Parse(TimestampLayout, column(0))
+	if err != nil {
+		return Transaction{}, err
+	}
+	var amounts [4]float64
+	for i, c := range []int{3, 5, 7, 8} {
+		if column(c) == "" {
+			continue
+		}
+		if amounts[i], err = strconv.ParseFloat(column(c), 64); err != nil {
+			return Transaction{}, fmt.Errorf("invalid %s: %w", columnNames[c], err)
+		}
+	}
+	return Transaction{
+		Timestamp:       timestamp,
+		Description:     column(1),
+		Currency:        column(2),
+		Amount:          amounts[0],
+		ToCurrency:      column(4),
+		ToAmount:        amounts[1],
+		NativeCurrency:  column(6),
+		NativeAmount:    amounts[2],
+		NativeAmountUSD: amounts[3],
+		Kind:            column(9),
+	}, nil
+}
@@ -0,0 +1,48 @@
+package tracker_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+)
+
+// TestReadTransactionsFiles merges the march export with the april one,
+// which repeats the last two weeks of march and adds a third cashback in the
+// same second as the two march already has.
+func TestReadTransactionsFiles(t *testing.T) {
+	march := filepath.Join("testdata", "merge", "march.csv")
+	april := filepath.Join("testdata", "merge", "april.csv")
+	merged, skipped, err := tracker.ReadTransactionsFiles(march, april)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 6 || skipped != 3 {
+		t.Fatalf("merged %d transactions and skipped %d, want 6 and 3", len(merged), skipped)
+	}
+	var cro float64
+	for i, transaction := range merged {
+		if i > 0 && transaction.Timestamp.Before(merged[i-1].Timestamp) {
+			t.Errorf("transaction %d at %s is older than the one before it", i, transaction.Timestamp)
+		}
+		cro += transaction.CRODelta()
+	}
+	if cro != 191.5 {
+		t.Errorf("merged transactions add up to %g CRO, want 191.5", cro)
+	}
+
+	// the order of the exports doesn't matter
+	reversed, skipped, err := tracker.ReadTransactionsFiles(april, march)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reversed) != 6 || skipped != 3 {
+		t.Errorf("merged %d transactions and skipped %d in reverse, want 6 and 3", len(reversed), skipped)
+	}
+
+	_, _, err = tracker.ReadTransactionsFiles(march, filepath.Join("testdata", "exports", "malformed_amount.csv"))
+	if err == nil || !strings.Contains(err.Error(), "malformed_amount.csv: line") {
+		t.Errorf("err = %v, want the malformed export and line", err)
+	}
+}
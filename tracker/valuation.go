@@ -0,0 +1,160 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// DefaultSnapshotSheet is the tab portfolio snapshots are appended to.
+const DefaultSnapshotSheet = "Snapshots"
+
+// Snapshot is the value of the portfolio at a point in time.
+type Snapshot struct {
+	Time time.Time
+	ROI
+}
+
+// Revalue values the purchases already in the sheet at the current price by
+// updating the prices tab. Tables imported before the prices tab existed
+// embed their price in every row; their percent change formulas are
+// rewritten to reference the tab instead.
+func (t *TransactionImporter) Revalue(ctx context.Context, prices PriceProvider) (ROI, error) {
+	purchases, formulas, err := t.readTable(ctx)
+	if err != nil {
+		return ROI{}, err
+	}
+	price, err := prices.Price(ctx, "CRO", t.fiat)
+	if err != nil {
+		return ROI{}, fmt.Errorf("failed to get CRO price; %w", err)
+	}
+	currentPrice, err := t.quotePrice(ctx, price)
+	if err != nil {
+		return ROI{}, err
+	}
+
+	var values [][]interface{}
+	stale := false
+	for i, p := range purchases {
+		row := NewRowData(p, t.startRowIndex+1+int64(i), t.startColumnIndex, currentPrice)
+		values = append(values, []interface{}{row.PercentChange})
+		stale = stale || formulas[i] != row.PercentChange
+	}
+	if stale {
+		rangez := SheetRange(t.sheetName, t.cell(percentChangeColumn, t.startRowIndex+1))
+		_, err = t.Googlesheet.Spreadsheets.Values.Update(t.SpreadsheetID, rangez, &sheets.ValueRange{Values: values}).ValueInputOption("USER_ENTERED").Context(ctx).Do()
+		if err != nil {
+			return ROI{}, fmt.Errorf("failed to update valuation; %w", err)
+		}
+		fmt.Fprintf(t.Log, "pointed %d rows at %q\n", len(values), t.pricesSheet)
+	}
+	return ComputeROI(purchases, price), nil
+}
+
+// readTable reads the purchases and their percent change formulas back from
+// the sheet, stopping at the summary row.
+func (t *TransactionImporter) readTable(ctx context.Context) ([]Purchase, []string, error) {
+	rangez, values, err := t.readRows(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil, fmt.Errorf("no table at %s, import transactions first", rangez)
+	}
+	purchases, formulas := tableRows(values)
+	return purchases, formulas, nil
+}
+
+// readRows reads the table as written, header and summary row included.
+func (t *TransactionImporter) readRows(ctx context.Context) (string, [][]interface{}, error) {
+	rangez := SheetRange(t.sheetName, fmt.Sprintf("%s:%s",
+		t.cell(fiatColumn, t.startRowIndex),
+		ColumnName(t.startColumnIndex+purchasedColumn),
+	))
+	// formulas are read as written, which tells the summary row apart
+	resp, err := t.Googlesheet.Spreadsheets.Values.Get(t.SpreadsheetID, rangez).ValueRenderOption("FORMULA").Context(ctx).Do()
+	if err != nil {
+		return rangez, nil, fmt.Errorf("failed to read %s; %w", rangez, err)
+	}
+	return rangez, resp.Values, nil
+}
+
+// tableRows parses the purchase rows of a table read by readRows, which
+// end at the summary row.
+func tableRows(values [][]interface{}) ([]Purchase, []string) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	var purchases []Purchase
+	var formulas []string
+	for _, row := range values[1:] {
+		if len(row) < 2 {
+			break
+		}
+		fiat, ok := row[fiatColumn].(float64)
+		if !ok {
+			break
+		}
+		cro, ok := row[croColumn].(float64)
+		if !ok {
+			break
+		}
+		formula := ""
+		if len(row) > int(percentChangeColumn) {
+			formula, _ = row[percentChangeColumn].(string)
+		}
+		var purchased interface{}
+		if len(row) > int(purchasedColumn) {
+			purchased = row[purchasedColumn]
+		}
+		purchases = append(purchases, Purchase{Timestamp: sheetTime(purchased), Fiat: fiat, CRO: cro})
+		formulas = append(formulas, formula)
+	}
+	return purchases, formulas
+}
+
+// sheetEpoch is day zero of the serial numbers sheets render dates as.
+var sheetEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// sheetTime parses a purchased cell, which sheets turn into a serial number
+// of days when they recognise the timestamp written. It is the zero time
+// when the cell holds neither.
+func sheetTime(value interface{}) time.Time {
+	switch v := value.(type) {
+	case float64:
+		return sheetEpoch.Add(time.Duration(v * 24 * float64(time.Hour))).Round(time.Second)
+	case string:
+		timestamp, err := time.Parse(TimestampLayout, v)
+		if err == nil {
+			return timestamp
+		}
+	}
+	return time.Time{}
+}
+
+// AppendSnapshot adds a row to the snapshot tab, adding the tab and its
+// header first when the spreadsheet doesn't have it yet.
+func (t *TransactionImporter) AppendSnapshot(ctx context.Context, sheetName string, s Snapshot) error {
+	_, added, err := t.ensureSheet(ctx, sheetName)
+	if err != nil {
+		return err
+	}
+	var rows [][]interface{}
+	if added {
+		rows = append(rows, []interface{}{
+			"Time", "CRO", fmt.Sprintf("%s Invested", t.fiat), "CRO Price",
+			fmt.Sprintf("%s Value", t.fiat), fmt.Sprintf("%s Gain", t.fiat), "Return",
+		})
+	}
+	rows = append(rows, []interface{}{
+		s.Time.UTC().Format(TimestampLayout), s.CRO, s.Invested, s.Price, s.Value, s.Gain, s.Return,
+	})
+	_, err = t.Googlesheet.Spreadsheets.Values.Append(t.SpreadsheetID, SheetRange(sheetName, "A1"), &sheets.ValueRange{Values: rows}).
+		ValueInputOption("USER_ENTERED").InsertDataOption("INSERT_ROWS").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to append snapshot to %q; %w", sheetName, err)
+	}
+	return nil
+}
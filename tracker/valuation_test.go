@@ -0,0 +1,169 @@
+package tracker_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igaskin/crypto-tracker/tracker"
+	"github.com/igaskin/crypto-tracker/tracker/sheetstest"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestRevalue(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI")
+
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{
+		SpreadsheetID: "sheet",
+		StartRow:      5,
+		StartColumn:   "C",
+	})
+	ctx := context.Background()
+	if err := importer.Import(ctx, strings.NewReader(transactionsCSV), fixedPrice(0.25)); err != nil {
+		t.Fatal(err)
+	}
+	imported := server.Grid("sheet", "ROI")
+	roi, err := importer.Revalue(ctx, fixedPrice(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := tracker.ComputeROI([]tracker.Purchase{{Fiat: 50, CRO: 300}, {Fiat: 20, CRO: 100}}, 0.5); roi != want {
+		t.Errorf("roi = %+v, want %+v", roi, want)
+	}
+	if got := sheetstest.FormatValue(server.Cell("sheet", "Prices!C2").UserEnteredValue); got != "0.5" {
+		t.Errorf("price = %s, want 0.5", got)
+	}
+	// the rows already reference the price
+	if got := server.Grid("sheet", "ROI"); !reflect.DeepEqual(got, imported) {
+		t.Errorf("table changed to %q", got)
+	}
+}
+
+func TestRevalueLegacyTable(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI")
+	service, err := server.Service(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// as imported before the prices tab, valued at a literal price
+	_, err = service.Spreadsheets.Values.Update("sheet", "ROI!A1", &sheets.ValueRange{Values: [][]interface{}{
+		{"USD", "CRO", "CRO Price", "Percent Change", "USD Change"},
+		{50, 300, "=(DIVIDE(A2,B2))", "=(DIVIDE(MINUS(0.25,C2),0.25))", "=MULTIPLY(A2,D2)"},
+		{"=SUM(A1:A2)", "=SUM(B1:B2)", "=AVERAGE(C1:C2)", "=MINUS(DIVIDE(SUM(A3,E3), ABS(A3)),1)", "=SUM(E1:E2)"},
+	}}).ValueInputOption("USER_ENTERED").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet"})
+	if _, err := importer.Revalue(context.Background(), fixedPrice(0.5)); err != nil {
+		t.Fatal(err)
+	}
+	for ref, want := range map[string]string{
+		"ROI!D2":    "=(DIVIDE(MINUS(Prices!$C$2,C2),Prices!$C$2))",
+		"ROI!D3":    "=MINUS(DIVIDE(SUM(A3,E3), ABS(A3)),1)",
+		"Prices!C2": "0.5",
+	} {
+		if got := sheetstest.FormatValue(server.Cell("sheet", ref).UserEnteredValue); got != want {
+			t.Errorf("%s = %q, want %q", ref, got, want)
+		}
+	}
+}
+
+type pricesByPair map[string]float64
+
+func (p pricesByPair) Price(ctx context.Context, asset, fiat string) (float64, error) {
+	price, ok := p[asset+"/"+fiat]
+	if !ok {
+		return 0, fmt.Errorf("no price for %s/%s", asset, fiat)
+	}
+	return price, nil
+}
+
+func TestRefreshPrices(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI")
+	ctx := context.Background()
+
+	usd := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet"})
+	if err := usd.Import(ctx, strings.NewReader(transactionsCSV), fixedPrice(0.25)); err != nil {
+		t.Fatal(err)
+	}
+	eur := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet", SheetName: "ROI EUR", Fiat: "EUR"})
+	if err := eur.Import(ctx, strings.NewReader(transactionsCSV), fixedPrice(0.2)); err != nil {
+		t.Fatal(err)
+	}
+	// both tables share the prices tab
+	if got := server.Cell("sheet", "'ROI EUR'!D2").UserEnteredValue; !strings.Contains(*got.FormulaValue, "Prices!$C$3") {
+		t.Errorf("EUR table references %s, want Prices!$C$3", *got.FormulaValue)
+	}
+
+	quotes, err := usd.RefreshPrices(ctx, pricesByPair{"CRO/USD": 0.5, "CRO/EUR": 0.4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quotes) != 2 || quotes[0].Asset != "CRO" || quotes[0].Fiat != "USD" || quotes[1].Fiat != "EUR" {
+		t.Errorf("quotes = %+v", quotes)
+	}
+	var got [][]string
+	for _, row := range server.Grid("sheet", tracker.DefaultPricesSheet) {
+		got = append(got, row[:3])
+	}
+	want := [][]string{{"Asset", "Fiat", "Price"}, {"CRO", "USD", "0.5"}, {"CRO", "EUR", "0.4"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("prices = %q, want %q", got, want)
+	}
+
+	if _, err := usd.RefreshPrices(ctx, pricesByPair{"CRO/USD": 0.5}); err == nil {
+		t.Error("expected the missing EUR price to fail the refresh")
+	}
+}
+
+func TestRevalueWithoutTable(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI")
+
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet"})
+	if _, err := importer.Revalue(context.Background(), fixedPrice(0.5)); err == nil || !strings.Contains(err.Error(), "import transactions first") {
+		t.Errorf("err = %v, want a missing table error", err)
+	}
+}
+
+func TestAppendSnapshot(t *testing.T) {
+	server := sheetstest.NewServer()
+	defer server.Close()
+	server.AddSpreadsheet("sheet", "ROI")
+
+	importer := newImporter(t, server, tracker.TransactionImporterOpts{SpreadsheetID: "sheet", Fiat: "EUR"})
+	ctx := context.Background()
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i, price := range []float64{0.25, 0.5} {
+		snapshot := tracker.Snapshot{
+			Time: start.Add(time.Duration(i) * time.Hour),
+			ROI:  tracker.ComputeROI([]tracker.Purchase{{Fiat: 50, CRO: 400}}, price),
+		}
+		if err := importer.AppendSnapshot(ctx, tracker.DefaultSnapshotSheet, snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := [][]string{
+		{"Time", "CRO", "EUR Invested", "CRO Price", "EUR Value", "EUR Gain", "Return"},
+		{"2021-06-01 12:00:00", "400", "50", "0.25", "100", "50", "1"},
+		{"2021-06-01 13:00:00", "400", "50", "0.5", "200", "150", "3"},
+	}
+	got := server.Grid("sheet", tracker.DefaultSnapshotSheet)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("grid = %q, want %q", got, want)
+	}
+}